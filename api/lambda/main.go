@@ -3,26 +3,55 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	logger "github.com/lenoobz/aws-lambda-logger"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/config"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/queue"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/repos"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/scraper"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/assets"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/checkpoint"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/failure"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/profile"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/report"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/sector"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/shard"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/symbol"
 )
 
+// ScrapeResponse is the lambda result, the run report along with the scraped profiles in dry runs.
+// Coordinator runs don't scrape, they return the shards to hand out to the workers instead
+type ScrapeResponse struct {
+	*entities.RunReport
+	DryRun   bool                        `json:"dryRun,omitempty"`
+	Profiles []*models.AssetProfileModel `json:"profiles,omitempty"`
+	Shards   []*entities.Shard           `json:"shards,omitempty"`
+}
+
+// triggerEnv selects the handler the lambda starts with, "sqs" for the queue handler
+const triggerEnv = "SCRAPER_TRIGGER"
+
 func main() {
+	if os.Getenv(triggerEnv) == "sqs" {
+		lambda.Start(sqsHandler)
+		return
+	}
+
 	lambda.Start(lambdaHandler)
 }
 
-func lambdaHandler(ctx context.Context) ([]string, error) {
+func lambdaHandler(ctx context.Context, event ScrapeEvent) (*ScrapeResponse, error) {
 	log.Println("lambda handler is called")
 
-	appConf := config.AppConf
+	event, err := event.prepare()
+	if err != nil {
+		return nil, err
+	}
 
 	// create new logger
 	zap, err := logger.NewZapLogger()
@@ -31,36 +60,182 @@ func lambdaHandler(ctx context.Context) ([]string, error) {
 	}
 	defer zap.Close()
 
-	// create new repository
-	assetProfileRepo, err := repos.NewAssetProfileMongo(nil, zap, &appConf.Mongo)
+	zap.Info(ctx, "scrape event", "mode", event.Mode, "source", event.Source, "tickers", event.Tickers, "pageSize", event.PageSize, "limit", event.Limit, "shardSize", event.ShardSize, "dryRun", event.DryRun)
+
+	return scrape(ctx, zap, event)
+}
+
+// scrape runs the scrape selected by a validated event
+func scrape(ctx context.Context, zap logger.ContextLog, event ScrapeEvent) (*ScrapeResponse, error) {
+	// only the scheduled checkpoint scrapes drain the failed tickers
+	job, profileMemory, shardService, closeJob := newJob(zap, event.DryRun, event.Mode == ModeCheckpoint)
+	defer closeJob()
+
+	if event.Mode == ModeCoordinate {
+		return coordinate(ctx, shardService, event)
+	}
+
+	var runReport *entities.RunReport
+	switch event.Mode {
+	case ModeTickers:
+		runReport = job.ScrapeAssetProfilesBySourceTickers(ctx, event.Source, event.Tickers)
+	case ModeSource:
+		runReport = job.ScrapeAllAssetProfilesBySource(ctx, event.Source)
+	case ModeCheckpoint:
+		runReport = job.ScrapeAssetProfilesBySourceFromCheckpoint(ctx, event.Source, event.PageSize)
+	case ModeStale:
+		runReport = job.ScrapeStaleAssetProfilesBySource(ctx, event.Source, event.Limit)
+	case ModeFailed:
+		runReport = job.ScrapeFailedAssetProfiles(ctx, event.Source, event.Limit)
+	case ModeShard:
+		runReport = job.ScrapeAssetProfilesByShard(ctx, event.Shard)
+	}
+
+	response := &ScrapeResponse{
+		RunReport: runReport,
+		DryRun:    event.DryRun,
+	}
+
+	if profileMemory != nil {
+		response.Profiles = profileMemory.FindAllAssetProfileModels()
+	}
+
+	return response, nil
+}
+
+// coordinate splits the assets of the source into shards and returns them, for the caller
+// to invoke a worker per shard
+func coordinate(ctx context.Context, shardService *shard.Service, event ScrapeEvent) (*ScrapeResponse, error) {
+	shards, err := shardService.CreateShards(ctx, event.Source, event.ShardSize, queue.NewShardMemoryQueue())
 	if err != nil {
-		log.Fatal("create asset profile mongo failed")
+		return nil, err
+	}
+
+	return &ScrapeResponse{
+		DryRun: event.DryRun,
+		Shards: shards,
+	}, nil
+}
+
+// newJob creates a scraper job along with its repositories, and a func closing all of them.
+// Dry runs read the assets, the symbol overrides and the sector mappings from mongo but keep everything they write in the returned memory
+func newJob(zap logger.ContextLog, dryRun bool, drainFailedTickers bool) (*scraper.AssetProfileScraper, *repos.AssetProfileMemory, *shard.Service, func()) {
+	appConf := config.AppConf
+
+	var closers []func()
+	closeAll := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
 	}
-	defer assetProfileRepo.Close()
 
 	// create new repository
 	assetRepo, err := repos.NewAssetMongo(nil, zap, &appConf.Mongo)
 	if err != nil {
 		log.Fatal("create asset mongo failed")
 	}
-	defer assetRepo.Close()
+	closers = append(closers, assetRepo.Close)
 
 	// create new repository
-	checkpointRepo, err := repos.NewCheckpointMongo(nil, zap, &appConf.Mongo)
+	symbolRepo, err := repos.NewSymbolOverrideMongo(nil, zap, &appConf.Mongo)
 	if err != nil {
-		log.Fatal("create checkpoint mongo failed")
+		log.Fatal("create symbol override mongo failed")
+	}
+	closers = append(closers, symbolRepo.Close)
+
+	// create new repository
+	sectorRepo, err := repos.NewSectorMappingMongo(nil, zap, &appConf.Mongo)
+	if err != nil {
+		log.Fatal("create sector mapping mongo failed")
+	}
+	closers = append(closers, sectorRepo.Close)
+
+	var assetProfileRepo profile.Repo
+	var checkpointRepo checkpoint.Repo
+	var failedTickerRepo failure.Repo
+	var runReportRepo report.Repo
+	var shardRepo shard.Repo
+	var profileMemory *repos.AssetProfileMemory
+
+	if dryRun {
+		profileMemory = repos.NewAssetProfileMemory(zap)
+
+		assetProfileRepo = profileMemory
+		checkpointRepo = repos.NewCheckpointMemory(zap)
+		failedTickerRepo = repos.NewFailedTickerMemory(zap)
+		runReportRepo = repos.NewRunReportMemory(zap)
+		shardRepo = repos.NewShardMemory(zap)
+	} else {
+		// create new repository
+		assetProfileMongo, err := repos.NewAssetProfileMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create asset profile mongo failed")
+		}
+		closers = append(closers, assetProfileMongo.Close)
+
+		// create new repository
+		checkpointMongo, err := repos.NewCheckpointMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create checkpoint mongo failed")
+		}
+		closers = append(closers, checkpointMongo.Close)
+
+		// create new repository
+		failedTickerMongo, err := repos.NewFailedTickerMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create failed ticker mongo failed")
+		}
+		closers = append(closers, failedTickerMongo.Close)
+
+		// create new repository
+		runReportMongo, err := repos.NewRunReportMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create run report mongo failed")
+		}
+		closers = append(closers, runReportMongo.Close)
+
+		// create new repository
+		shardMongo, err := repos.NewShardMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create shard mongo failed")
+		}
+		closers = append(closers, shardMongo.Close)
+
+		assetProfileRepo = assetProfileMongo
+		checkpointRepo = checkpointMongo
+		failedTickerRepo = failedTickerMongo
+		runReportRepo = runReportMongo
+		shardRepo = shardMongo
 	}
-	defer checkpointRepo.Close()
 
 	// create new service
 	checkpointService := checkpoint.NewService(checkpointRepo, zap)
 	assetService := assets.NewService(assetRepo, *checkpointService, zap)
-	profileService := profile.NewService(assetProfileRepo, zap)
+	profileService := profile.NewService(assetProfileRepo, consts.DELETE_AFTER_NOT_FOUND, zap)
+	failedTickerService := failure.NewService(failedTickerRepo, consts.QUARANTINE_AFTER_FAILURES, zap)
+	runReportService := report.NewService(runReportRepo, zap)
+	shardService := shard.NewService(shardRepo, assetService, zap)
+	symbolService := symbol.NewService(symbolRepo, zap)
+	sectorService := sector.NewService(sectorRepo, zap)
+
+	opts := []scraper.ScraperOption{
+		scraper.WithFailedTickerService(failedTickerService),
+		scraper.WithRunReportService(runReportService),
+		scraper.WithRefreshPolicy(appConf.Refresh.RefreshPolicy()),
+		scraper.WithYahooSites(appConf.Yahoo.YahooSites()),
+		scraper.WithRandomDelay(appConf.Yahoo.RandomDelay()),
+		scraper.WithShardService(shardService),
+		scraper.WithSymbolService(symbolService),
+		scraper.WithSectorService(sectorService),
+	}
+
+	if drainFailedTickers {
+		opts = append(opts, scraper.WithFailedTickerDrain(consts.FAILED_TICKERS_DRAIN_SIZE))
+	}
 
 	// create new scraper job
-	job := scraper.NewAssetProfileScraper(assetService, profileService, zap)
-	job.ScrapeAssetProfilesBySourceFromCheckpoint(consts.TIP_RANK_SOURCE, consts.PAGE_SIZE)
+	job := scraper.NewAssetProfileScraper(assetService, profileService, zap, opts...)
+	closers = append(closers, func() { job.Close() })
 
-	tickers := job.Close()
-	return tickers, nil
+	return job, profileMemory, shardService, closeAll
 }
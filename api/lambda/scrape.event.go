@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// ErrInvalidEvent is returned for scrape events that fail validation
+var ErrInvalidEvent = errors.New("invalid scrape event")
+
+// Scrape modes
+const (
+	// ModeTickers scrapes the tickers of the event
+	ModeTickers = "tickers"
+	// ModeSource scrapes all the assets of the source
+	ModeSource = "source"
+	// ModeCheckpoint scrapes the next checkpoint page of the source
+	ModeCheckpoint = "checkpoint"
+	// ModeStale scrapes the assets of the source most due for a refresh
+	ModeStale = "stale"
+	// ModeFailed scrapes the failed tickers of the source
+	ModeFailed = "failed"
+	// ModeCoordinate splits all the assets of the source into shards for the workers
+	ModeCoordinate = "coordinate"
+	// ModeShard scrapes the assets of the shard of the event
+	ModeShard = "shard"
+)
+
+// eventBridgeSourcePrefix starts the source of the events sent by AWS services, the scheduled
+// events come with the source "aws.events" which is not a source of our assets
+const eventBridgeSourcePrefix = "aws."
+
+// ScrapeEvent is the payload the lambda is invoked with. An empty payload, or a scheduled
+// event, scrapes the TipRank assets most due for a refresh. The source of a tickers event is
+// optional: the failed tickers are recorded for the drain of that source, and not at all without one
+type ScrapeEvent struct {
+	Mode      string          `json:"mode,omitempty"`
+	Source    string          `json:"source,omitempty"`
+	Tickers   []string        `json:"tickers,omitempty"`
+	PageSize  int64           `json:"pageSize,omitempty"`
+	Limit     int64           `json:"limit,omitempty"`
+	ShardSize int64           `json:"shardSize,omitempty"`
+	Shard     *entities.Shard `json:"shard,omitempty"`
+	DryRun    bool            `json:"dryRun,omitempty"`
+}
+
+// withDefaults returns a copy of the event with the missing values defaulted
+func (e ScrapeEvent) withDefaults() ScrapeEvent {
+	if e.Mode == "" {
+		e.Mode = ModeStale
+	}
+	e.Mode = strings.ToLower(e.Mode)
+
+	if isEventBridgeSource(e.Source) {
+		e.Source = ""
+	}
+
+	// the shards carry their source
+	if e.Source == "" && e.Mode != ModeTickers && e.Mode != ModeShard {
+		e.Source = consts.TIP_RANK_SOURCE
+	}
+
+	if e.PageSize == 0 && e.Mode == ModeCheckpoint {
+		e.PageSize = consts.PAGE_SIZE
+	}
+
+	if e.Limit == 0 && e.Mode == ModeFailed {
+		e.Limit = consts.FAILED_TICKERS_DRAIN_SIZE
+	}
+
+	if e.Limit == 0 && e.Mode == ModeStale {
+		e.Limit = consts.PAGE_SIZE
+	}
+
+	if e.ShardSize == 0 && e.Mode == ModeCoordinate {
+		e.ShardSize = consts.SHARD_SIZE
+	}
+
+	return e
+}
+
+// isEventBridgeSource reports whether source is the source of an event sent by an AWS service
+func isEventBridgeSource(source string) bool {
+	return strings.HasPrefix(strings.ToLower(source), eventBridgeSourcePrefix)
+}
+
+// prepare defaults and validates the event the lambda is invoked with
+func (e ScrapeEvent) prepare() (ScrapeEvent, error) {
+	e = e.withDefaults()
+	if err := e.Validate(); err != nil {
+		return e, err
+	}
+
+	return e, nil
+}
+
+// Validate checks the event once defaulted
+func (e ScrapeEvent) Validate() error {
+	if isEventBridgeSource(e.Source) {
+		return fmt.Errorf("%w: source %q is the source of an AWS event, not of assets", ErrInvalidEvent, e.Source)
+	}
+
+	switch e.Mode {
+	case ModeTickers:
+		if len(e.Tickers) == 0 {
+			return fmt.Errorf("%w: mode %q needs at least one ticker", ErrInvalidEvent, e.Mode)
+		}
+		for i, ticker := range e.Tickers {
+			if strings.TrimSpace(ticker) == "" {
+				return fmt.Errorf("%w: ticker %d is blank", ErrInvalidEvent, i)
+			}
+		}
+	case ModeSource, ModeCheckpoint, ModeStale, ModeFailed, ModeCoordinate:
+		if len(e.Tickers) > 0 {
+			return fmt.Errorf("%w: tickers are only allowed in mode %q", ErrInvalidEvent, ModeTickers)
+		}
+	case ModeShard:
+		if e.Shard == nil || e.Shard.JobID == "" || e.Shard.Source == "" {
+			return fmt.Errorf("%w: mode %q needs a shard with a job id and a source", ErrInvalidEvent, e.Mode)
+		}
+		if len(e.Tickers) > 0 || e.Source != "" {
+			return fmt.Errorf("%w: mode %q takes its tickers from the shard", ErrInvalidEvent, e.Mode)
+		}
+	default:
+		return fmt.Errorf("%w: mode %q is not one of %q, %q, %q, %q, %q, %q or %q", ErrInvalidEvent, e.Mode, ModeTickers, ModeSource, ModeCheckpoint, ModeStale, ModeFailed, ModeCoordinate, ModeShard)
+	}
+
+	if e.Shard != nil && e.Mode != ModeShard {
+		return fmt.Errorf("%w: shard is only allowed in mode %q", ErrInvalidEvent, ModeShard)
+	}
+
+	if e.ShardSize < 0 || e.ShardSize > consts.MAX_PAGE_SIZE {
+		return fmt.Errorf("%w: shard size %d is not between 1 and %d", ErrInvalidEvent, e.ShardSize, consts.MAX_PAGE_SIZE)
+	}
+
+	if e.ShardSize > 0 && e.Mode != ModeCoordinate {
+		return fmt.Errorf("%w: shard size is only allowed in mode %q", ErrInvalidEvent, ModeCoordinate)
+	}
+
+	if e.PageSize < 0 || e.PageSize > consts.MAX_PAGE_SIZE {
+		return fmt.Errorf("%w: page size %d is not between 1 and %d", ErrInvalidEvent, e.PageSize, consts.MAX_PAGE_SIZE)
+	}
+
+	if e.PageSize > 0 && e.Mode != ModeCheckpoint {
+		return fmt.Errorf("%w: page size is only allowed in mode %q", ErrInvalidEvent, ModeCheckpoint)
+	}
+
+	if e.Limit < 0 {
+		return fmt.Errorf("%w: limit %d is negative", ErrInvalidEvent, e.Limit)
+	}
+
+	if e.Limit > 0 && e.Mode != ModeFailed && e.Mode != ModeStale {
+		return fmt.Errorf("%w: limit is only allowed in modes %q and %q", ErrInvalidEvent, ModeFailed, ModeStale)
+	}
+
+	if e.Limit > consts.MAX_PAGE_SIZE && e.Mode == ModeStale {
+		return fmt.Errorf("%w: limit %d is more than %d", ErrInvalidEvent, e.Limit, consts.MAX_PAGE_SIZE)
+	}
+
+	if e.DryRun && e.Mode == ModeFailed {
+		return fmt.Errorf("%w: mode %q cannot dry run, the failed tickers are not read in dry runs", ErrInvalidEvent, ModeFailed)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+func TestScrapeEventDefaults(t *testing.T) {
+	tests := []struct {
+		event ScrapeEvent
+		want  ScrapeEvent
+	}{
+		{
+			event: ScrapeEvent{},
+			want:  ScrapeEvent{Mode: ModeStale, Source: consts.TIP_RANK_SOURCE, Limit: consts.PAGE_SIZE},
+		},
+		{
+			event: ScrapeEvent{Mode: ModeCheckpoint},
+			want:  ScrapeEvent{Mode: ModeCheckpoint, Source: consts.TIP_RANK_SOURCE, PageSize: consts.PAGE_SIZE},
+		},
+		{
+			event: ScrapeEvent{Mode: "FAILED", Source: "OTHER"},
+			want:  ScrapeEvent{Mode: ModeFailed, Source: "OTHER", Limit: consts.FAILED_TICKERS_DRAIN_SIZE},
+		},
+		{
+			event: ScrapeEvent{Mode: ModeCoordinate},
+			want:  ScrapeEvent{Mode: ModeCoordinate, Source: consts.TIP_RANK_SOURCE, ShardSize: consts.SHARD_SIZE},
+		},
+		{
+			event: ScrapeEvent{Mode: ModeShard, Shard: &entities.Shard{JobID: "job", Source: "TIPRANK"}},
+			want:  ScrapeEvent{Mode: ModeShard, Shard: &entities.Shard{JobID: "job", Source: "TIPRANK"}},
+		},
+		{
+			event: ScrapeEvent{Mode: ModeTickers, Tickers: []string{"AAPL"}, DryRun: true},
+			want:  ScrapeEvent{Mode: ModeTickers, Tickers: []string{"AAPL"}, DryRun: true},
+		},
+	}
+
+	for _, test := range tests {
+		got := test.event.withDefaults()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("withDefaults(%+v) = %+v, want %+v", test.event, got, test.want)
+		}
+		if err := got.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v, want nil", got, err)
+		}
+	}
+}
+
+func TestScheduledEventScrapesTipRank(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/scheduled.event.json")
+	if err != nil {
+		t.Fatalf("read event: %v", err)
+	}
+
+	// the lambda runtime decodes the scheduled event into the scrape event as is
+	var event ScrapeEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+
+	got, err := event.prepare()
+	if err != nil {
+		t.Fatalf("prepare(%+v) = %v, want nil", event, err)
+	}
+
+	want := ScrapeEvent{Mode: ModeStale, Source: consts.TIP_RANK_SOURCE, Limit: consts.PAGE_SIZE}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prepare(%+v) = %+v, want %+v", event, got, want)
+	}
+}
+
+func TestScrapeEventValidate(t *testing.T) {
+	invalid := []ScrapeEvent{
+		{Mode: "backfill"},
+		{Mode: ModeTickers},
+		{Mode: ModeTickers, Tickers: []string{"AAPL", " "}},
+		{Mode: ModeSource, Tickers: []string{"AAPL"}},
+		{Mode: ModeCheckpoint, PageSize: -1},
+		{Mode: ModeCheckpoint, PageSize: consts.MAX_PAGE_SIZE + 1},
+		{Mode: ModeSource, PageSize: 10},
+		{Mode: ModeFailed, Limit: -1},
+		{Mode: ModeCheckpoint, Limit: 10},
+		{Mode: ModeFailed, DryRun: true},
+		{Mode: ModeStale, Limit: consts.MAX_PAGE_SIZE + 1},
+		{Mode: ModeStale, PageSize: 10},
+		{Mode: ModeCoordinate, ShardSize: consts.MAX_PAGE_SIZE + 1},
+		{Mode: ModeStale, ShardSize: 10},
+		{Mode: ModeShard},
+		{Mode: ModeShard, Shard: &entities.Shard{Source: "TIPRANK"}},
+		{Mode: ModeShard, Source: "OTHER", Shard: &entities.Shard{JobID: "job", Source: "TIPRANK"}},
+		{Mode: ModeSource, Shard: &entities.Shard{JobID: "job", Source: "TIPRANK"}},
+	}
+
+	for _, event := range invalid {
+		err := event.withDefaults().Validate()
+		if !errors.Is(err, ErrInvalidEvent) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidEvent", event, err)
+		}
+	}
+
+	// the defaults drop the source of the AWS events, which is never a source of assets
+	event := ScrapeEvent{Mode: ModeSource, Source: "aws.events"}
+	if err := event.Validate(); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Validate(%+v) = %v, want ErrInvalidEvent", event, err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// SQSBatchResponse reports the messages of a batch to redeliver. aws-lambda-go v1.24
+// has no type for partial batch responses, the json matches the one lambda expects
+type SQSBatchResponse struct {
+	BatchItemFailures []SQSBatchItemFailure `json:"batchItemFailures"`
+}
+
+// SQSBatchItemFailure identifies a message to redeliver
+type SQSBatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// TickerMessage is the json body of a queue message requesting profile refreshes.
+// A body that is not a json object is read as a single ticker
+type TickerMessage struct {
+	Ticker  string   `json:"ticker,omitempty"`
+	Tickers []string `json:"tickers,omitempty"`
+}
+
+// tickerScraper scrapes the asset profiles of tickers
+type tickerScraper interface {
+	ScrapeAssetProfilesByTickers(ctx context.Context, tickers []string) *entities.RunReport
+}
+
+func sqsHandler(ctx context.Context, event events.SQSEvent) (SQSBatchResponse, error) {
+	log.Println("sqs handler is called")
+
+	// create new logger
+	zap, err := logger.NewZapLogger()
+	if err != nil {
+		log.Fatal("create app logger failed")
+	}
+	defer zap.Close()
+
+	job, _, _, closeJob := newJob(zap, false, false)
+	defer closeJob()
+
+	return handleSQSEvent(ctx, zap, job, event), nil
+}
+
+// handleSQSEvent scrapes the tickers of all the messages in a single run, and reports
+// the messages having a ticker that was not scraped so only those are redelivered.
+// The redelivery is the only retry: the tickers have no source, so the job neither records
+// their failures nor queues the ones the deadline left out for the failed ticker drain.
+// Malformed messages would never succeed, they are logged and dropped
+func handleSQSEvent(ctx context.Context, log logger.ContextLog, job tickerScraper, event events.SQSEvent) SQSBatchResponse {
+	messageTickers := make(map[string][]string)
+
+	var tickers []string
+	for _, message := range event.Records {
+		t, err := parseTickerMessage(message.Body)
+		if err != nil {
+			log.Error(ctx, "drop malformed message", "error", err, "messageId", message.MessageId)
+			continue
+		}
+
+		messageTickers[message.MessageId] = t
+		tickers = append(tickers, t...)
+	}
+
+	response := SQSBatchResponse{
+		BatchItemFailures: []SQSBatchItemFailure{},
+	}
+
+	if len(tickers) == 0 {
+		return response
+	}
+
+	runReport := job.ScrapeAssetProfilesByTickers(ctx, tickers)
+
+	scraped := make(map[string]bool)
+	for _, ticker := range runReport.ScrapedTickers {
+		scraped[ticker] = true
+	}
+
+	for _, message := range event.Records {
+		for _, ticker := range messageTickers[message.MessageId] {
+			if !scraped[ticker] {
+				response.BatchItemFailures = append(response.BatchItemFailures, SQSBatchItemFailure{ItemIdentifier: message.MessageId})
+				break
+			}
+		}
+	}
+
+	log.Info(ctx, "sqs batch scraped", "messages", len(event.Records), "tickers", len(tickers), "failedMessages", len(response.BatchItemFailures))
+	return response
+}
+
+// parseTickerMessage returns the tickers requested by a message body
+func parseTickerMessage(body string) ([]string, error) {
+	body = strings.TrimSpace(body)
+
+	if !strings.HasPrefix(body, "{") {
+		if body == "" || strings.ContainsAny(body, " \t\n,") {
+			return nil, fmt.Errorf("body %q is not a ticker", body)
+		}
+		return []string{body}, nil
+	}
+
+	var message TickerMessage
+	if err := json.Unmarshal([]byte(body), &message); err != nil {
+		return nil, fmt.Errorf("decode body failed: %w", err)
+	}
+
+	var tickers []string
+	for _, ticker := range append([]string{message.Ticker}, message.Tickers...) {
+		if ticker = strings.TrimSpace(ticker); ticker != "" {
+			tickers = append(tickers, ticker)
+		}
+	}
+
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("body %q has no ticker", body)
+	}
+
+	return tickers, nil
+}
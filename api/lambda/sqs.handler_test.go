@@ -0,0 +1,169 @@
+//go:build testing
+// +build testing
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/config"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/repos"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/scraper"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/scraper/fakeyahoo"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/assets"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/checkpoint"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/failure"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/profile"
+)
+
+// newTestScraper creates a scraper against a fake Yahoo server and in-memory repos,
+// recording its failed tickers to failedRepo
+func newTestScraper(t *testing.T, server *fakeyahoo.Server, failedRepo *repos.FailedTickerMemory) *scraper.AssetProfileScraper {
+	t.Helper()
+
+	baseURL := config.YahooBaseURL
+	if err := config.SetYahooBaseURL(server.URL); err != nil {
+		t.Fatalf("set yahoo base url: %v", err)
+	}
+
+	zap, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+
+	t.Cleanup(func() {
+		config.YahooBaseURL = baseURL
+		zap.Close()
+	})
+
+	checkpointService := checkpoint.NewService(repos.NewCheckpointMemory(zap), zap)
+	assetService := assets.NewService(repos.NewAssetMemory(zap), *checkpointService, zap)
+	profileService := profile.NewService(repos.NewAssetProfileMemory(zap), consts.DELETE_AFTER_NOT_FOUND, zap)
+	failedTickerService := failure.NewService(failedRepo, 0, zap)
+
+	return scraper.NewAssetProfileScraper(assetService, profileService, zap,
+		scraper.WithRetryPolicy(scraper.NoRetryPolicy()),
+		scraper.WithFailedTickerService(failedTickerService),
+	)
+}
+
+func TestHandleSQSEventReportsFailedMessages(t *testing.T) {
+	server := fakeyahoo.NewServer()
+	defer server.Close()
+
+	server.Script("AAPL", fakeyahoo.ProfilePage(&entities.AssetProfile{Sector: "Technology", Country: "United States"}))
+	server.Script("RY", fakeyahoo.ProfilePage(&entities.AssetProfile{Sector: "Financial Services", Country: "Canada"}))
+	server.Script("GONE", fakeyahoo.NotFound())
+
+	body, err := ioutil.ReadFile("testdata/sqs.event.json")
+	if err != nil {
+		t.Fatalf("read event: %v", err)
+	}
+
+	var event events.SQSEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+
+	zap, _ := logger.NewZapLogger()
+	defer zap.Close()
+
+	failedRepo := repos.NewFailedTickerMemory(zap)
+
+	job := newTestScraper(t, server, failedRepo)
+	defer job.Close()
+
+	response := handleSQSEvent(context.Background(), zap, job, event)
+
+	// only the GONE message is redelivered, the empty message is dropped
+	want := []SQSBatchItemFailure{{ItemIdentifier: "2e1424d4-f796-459a-8184-9c92662be6da"}}
+	if !reflect.DeepEqual(response.BatchItemFailures, want) {
+		t.Errorf("batch item failures = %+v, want %+v", response.BatchItemFailures, want)
+	}
+
+	// RY is requested by two messages but scraped once
+	if hits := server.Hits("RY"); hits != 1 {
+		t.Errorf("RY requested %d times, want 1", hits)
+	}
+
+	// GONE is retried by the redelivery only, not by the failed ticker drain too
+	if failed, _ := failedRepo.FindFailedTickersBySource(context.Background(), "", 0); len(failed) != 0 {
+		t.Errorf("failed tickers = %+v, want none", failed)
+	}
+}
+
+func TestHandleSQSEventRedeliversTickersNotAttemptedBeforeTheDeadline(t *testing.T) {
+	server := fakeyahoo.NewServer()
+	defer server.Close()
+
+	server.Script("AAPL", fakeyahoo.ProfilePage(&entities.AssetProfile{Sector: "Technology", Country: "United States"}))
+
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{
+			{MessageId: "aapl", Body: "AAPL"},
+			{MessageId: "ry", Body: `{"tickers": ["RY"]}`},
+		},
+	}
+
+	zap, _ := logger.NewZapLogger()
+	defer zap.Close()
+
+	failedRepo := repos.NewFailedTickerMemory(zap)
+
+	job := newTestScraper(t, server, failedRepo)
+	defer job.Close()
+
+	// the deadline is within the scraper's margin, so no ticker is requested
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	response := handleSQSEvent(ctx, zap, job, event)
+
+	want := []SQSBatchItemFailure{{ItemIdentifier: "aapl"}, {ItemIdentifier: "ry"}}
+	if !reflect.DeepEqual(response.BatchItemFailures, want) {
+		t.Errorf("batch item failures = %+v, want %+v", response.BatchItemFailures, want)
+	}
+
+	if hits := server.Hits("AAPL"); hits != 0 {
+		t.Errorf("AAPL requested %d times, want 0", hits)
+	}
+
+	// the redelivery retries the tickers, they are not queued for the failed ticker drain too
+	if failed, _ := failedRepo.FindFailedTickersBySource(context.Background(), "", 0); len(failed) != 0 {
+		t.Errorf("failed tickers = %+v, want none", failed)
+	}
+}
+
+func TestParseTickerMessage(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{body: "AAPL", want: []string{"AAPL"}},
+		{body: " RY.TO\n", want: []string{"RY.TO"}},
+		{body: `{"ticker": "AAPL"}`, want: []string{"AAPL"}},
+		{body: `{"ticker": "AAPL", "tickers": ["RY", " "]}`, want: []string{"AAPL", "RY"}},
+	}
+
+	for _, test := range tests {
+		got, err := parseTickerMessage(test.body)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseTickerMessage(%q) = %v, %v, want %v", test.body, got, err, test.want)
+		}
+	}
+
+	for _, body := range []string{"", "AAPL, RY", `{"tickers": []}`, `{"tickers": `} {
+		if _, err := parseTickerMessage(body); err == nil {
+			t.Errorf("parseTickerMessage(%q) succeeded, want an error", body)
+		}
+	}
+}
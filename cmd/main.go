@@ -1,19 +1,57 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"log"
+	"os"
 
 	logger "github.com/lenoobz/aws-lambda-logger"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/config"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/queue"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/repos"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/scraper"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/assets"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/checkpoint"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/failure"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/profile"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/report"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/sector"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/shard"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/symbol"
 )
 
 func main() {
+	replayDir := flag.String("replay", "", "replay saved profile pages from this directory instead of fetching them, requires -seed")
+	recordDir := flag.String("record", "", "save every fetched profile page to this directory")
+	seedFile := flag.String("seed", "", "dry run against in-memory repositories seeded from this JSON file of assets")
+	selector := flag.String("selector", "stale", "select the assets to scrape by staleness, \"stale\", or by checkpoint page, \"checkpoint\"")
+	migrateCheckpoints := flag.Bool("migrate-checkpoints", false, "move the global checkpoint to the per source checkpoint of the TIP_RANK source and exit")
+	coordinate := flag.Bool("coordinate", false, "split the TIP_RANK assets into shards for the workers instead of scraping them, with -seed the shards are worked in-process")
+	shardsFile := flag.String("shards", "", "write the shard descriptors of -coordinate to this file instead of stdout")
+	workShardsFile := flag.String("work-shards", "", "scrape the shards of the shard descriptors in this file")
+	flag.Parse()
+
+	if *selector != "stale" && *selector != "checkpoint" {
+		log.Fatalf("unknown selector %q", *selector)
+	}
+
+	if *coordinate && *workShardsFile != "" {
+		log.Fatal("-coordinate and -work-shards are exclusive")
+	}
+
+	if *replayDir != "" && *recordDir != "" {
+		log.Fatal("-replay and -record are exclusive")
+	}
+
+	// replayed pages would overwrite the stored profiles with stale ones
+	if *replayDir != "" && *seedFile == "" {
+		log.Fatal("-replay requires -seed, replays only run against in-memory repositories")
+	}
+
 	appConf := config.AppConf
 
 	// create new logger
@@ -23,34 +61,254 @@ func main() {
 	}
 	defer zap.Close()
 
-	// create new repository
-	assetProfileRepo, err := repos.NewAssetProfileMongo(nil, zap, &appConf.Mongo)
+	if *migrateCheckpoints {
+		migrateGlobalCheckpoint(zap, &appConf.Mongo)
+		return
+	}
+
+	var assetProfileRepo profile.Repo
+	var assetRepo assets.Repo
+	var checkpointRepo checkpoint.Repo
+	var failedTickerRepo failure.Repo
+	var runReportRepo report.Repo
+	var shardRepo shard.Repo
+	var symbolRepo symbol.Repo
+	var sectorRepo sector.Repo
+
+	if *seedFile != "" {
+		// dry run against in-memory repositories seeded from the assets file
+		assetMemory, err := repos.NewAssetMemoryFromFile(zap, *seedFile)
+		if err != nil {
+			log.Fatalf("seed asset memory failed: %v", err)
+		}
+
+		profileMemory := repos.NewAssetProfileMemory(zap)
+		defer printAssetProfiles(profileMemory)
+
+		failedTickerMemory := repos.NewFailedTickerMemory(zap)
+		assetMemory.JoinProfiles(profileMemory, failedTickerMemory)
+
+		assetRepo = assetMemory
+		assetProfileRepo = profileMemory
+		checkpointRepo = repos.NewCheckpointMemory(zap)
+		failedTickerRepo = failedTickerMemory
+		runReportRepo = repos.NewRunReportMemory(zap)
+		shardRepo = repos.NewShardMemory(zap)
+		symbolRepo = repos.NewSymbolOverrideMemory(zap)
+		sectorRepo = repos.NewSectorMappingMemory(zap)
+	} else {
+		// create new repository
+		assetProfileMongo, err := repos.NewAssetProfileMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create asset profile mongo failed")
+		}
+		defer assetProfileMongo.Close()
+
+		// create new repository
+		assetMongo, err := repos.NewAssetMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create asset mongo failed")
+		}
+		defer assetMongo.Close()
+
+		// create new repository
+		checkpointMongo, err := repos.NewCheckpointMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create checkpoint mongo failed")
+		}
+		defer checkpointMongo.Close()
+
+		// create new repository
+		failedTickerMongo, err := repos.NewFailedTickerMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create failed ticker mongo failed")
+		}
+		defer failedTickerMongo.Close()
+
+		// create new repository
+		runReportMongo, err := repos.NewRunReportMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create run report mongo failed")
+		}
+		defer runReportMongo.Close()
+
+		// create new repository
+		shardMongo, err := repos.NewShardMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create shard mongo failed")
+		}
+		defer shardMongo.Close()
+
+		// create new repository
+		symbolOverrideMongo, err := repos.NewSymbolOverrideMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create symbol override mongo failed")
+		}
+		defer symbolOverrideMongo.Close()
+
+		// create new repository
+		sectorMappingMongo, err := repos.NewSectorMappingMongo(nil, zap, &appConf.Mongo)
+		if err != nil {
+			log.Fatal("create sector mapping mongo failed")
+		}
+		defer sectorMappingMongo.Close()
+
+		assetRepo = assetMongo
+		assetProfileRepo = assetProfileMongo
+		checkpointRepo = checkpointMongo
+		failedTickerRepo = failedTickerMongo
+		runReportRepo = runReportMongo
+		shardRepo = shardMongo
+		symbolRepo = symbolOverrideMongo
+		sectorRepo = sectorMappingMongo
+	}
+
+	// create new service
+	checkpointService := checkpoint.NewService(checkpointRepo, zap)
+	assetService := assets.NewService(assetRepo, *checkpointService, zap)
+	profileService := profile.NewService(assetProfileRepo, consts.DELETE_AFTER_NOT_FOUND, zap)
+	failedTickerService := failure.NewService(failedTickerRepo, consts.QUARANTINE_AFTER_FAILURES, zap)
+	runReportService := report.NewService(runReportRepo, zap)
+	shardService := shard.NewService(shardRepo, assetService, zap)
+	symbolService := symbol.NewService(symbolRepo, zap)
+	sectorService := sector.NewService(sectorRepo, zap)
+
+	opts := []scraper.ScraperOption{
+		scraper.WithFailedTickerService(failedTickerService),
+		scraper.WithRunReportService(runReportService),
+		scraper.WithRefreshPolicy(appConf.Refresh.RefreshPolicy()),
+		scraper.WithYahooSites(appConf.Yahoo.YahooSites()),
+		scraper.WithRandomDelay(appConf.Yahoo.RandomDelay()),
+		scraper.WithShardService(shardService),
+		scraper.WithSymbolService(symbolService),
+		scraper.WithSectorService(sectorService),
+	}
+	if *selector == "checkpoint" {
+		// the stale selection already puts the failed tickers first
+		opts = append(opts, scraper.WithFailedTickerDrain(consts.FAILED_TICKERS_DRAIN_SIZE))
+	}
+	if *replayDir != "" {
+		opts = append(opts, scraper.WithReplayDir(*replayDir))
+	}
+	if *recordDir != "" {
+		opts = append(opts, scraper.WithRecordDir(*recordDir))
+	}
+
+	job := scraper.NewAssetProfileScraper(assetService, profileService, zap, opts...)
+	// job.ScrapeAllAssetProfilesBySource(context.Background(), consts.TIP_RANK_SOURCE)
+	defer job.Close()
+
+	if *coordinate {
+		if *seedFile != "" {
+			// the in-memory repositories are gone once the run exits, work the shards in-process
+			shardQueue := queue.NewShardMemoryQueue()
+			createShards(shardService, shardQueue)
+
+			for sh, ok := shardQueue.Receive(); ok; sh, ok = shardQueue.Receive() {
+				printJSON(job.ScrapeAssetProfilesByShard(context.Background(), sh))
+			}
+			return
+		}
+
+		publishShards(shardService, *shardsFile)
+		return
+	}
+
+	if *workShardsFile != "" {
+		for _, sh := range readShards(*workShardsFile) {
+			printJSON(job.ScrapeAssetProfilesByShard(context.Background(), sh))
+		}
+		return
+	}
+
+	var runReport *entities.RunReport
+	if *selector == "checkpoint" {
+		runReport = job.ScrapeAssetProfilesBySourceFromCheckpoint(context.Background(), consts.TIP_RANK_SOURCE, consts.PAGE_SIZE)
+	} else {
+		runReport = job.ScrapeStaleAssetProfilesBySource(context.Background(), consts.TIP_RANK_SOURCE, consts.PAGE_SIZE)
+	}
+
+	printJSON(runReport)
+}
+
+// migrateGlobalCheckpoint moves the checkpoint shared by all sources to the TIP_RANK source,
+// which was the only source paged through it, resuming at the page it last started
+func migrateGlobalCheckpoint(zap logger.ContextLog, mongoConf *config.MongoConfig) {
+	checkpointMongo, err := repos.NewCheckpointMongo(nil, zap, mongoConf)
 	if err != nil {
-		log.Fatal("create asset profile mongo failed")
+		log.Fatal("create checkpoint mongo failed")
 	}
-	defer assetProfileRepo.Close()
+	defer checkpointMongo.Close()
 
-	// create new repository
-	assetRepo, err := repos.NewAssetMongo(nil, zap, &appConf.Mongo)
+	// the page of the global checkpoint is resolved to the asset it follows
+	assetMongo, err := repos.NewAssetMongo(nil, zap, mongoConf)
 	if err != nil {
 		log.Fatal("create asset mongo failed")
 	}
-	defer assetRepo.Close()
+	defer assetMongo.Close()
 
-	// create new repository
-	checkpointRepo, err := repos.NewCheckpointMongo(nil, zap, &appConf.Mongo)
+	moved, err := checkpointMongo.MigrateGlobalCheckpoint(context.Background(), assetMongo, consts.TIP_RANK_SOURCE, consts.ASSET_PROFILE_JOB)
 	if err != nil {
-		log.Fatal("create checkpoint mongo failed")
+		log.Fatalf("migrate global checkpoint failed: %v", err)
 	}
-	defer checkpointRepo.Close()
 
-	// create new service
-	checkpointService := checkpoint.NewService(checkpointRepo, zap)
-	assetService := assets.NewService(assetRepo, *checkpointService, zap)
-	profileService := profile.NewService(assetProfileRepo, zap)
+	log.Printf("migrate global checkpoint done, moved: %v", moved)
+}
 
-	job := scraper.NewAssetProfileScraper(assetService, profileService, zap)
-	// job.ScrapeAllAssetProfilesBySource(consts.TIP_RANK_SOURCE)
-	job.ScrapeAssetProfilesBySourceFromCheckpoint(consts.TIP_RANK_SOURCE, consts.PAGE_SIZE)
-	defer job.Close()
+// createShards splits the TIP_RANK assets into shards and publishes them
+func createShards(shardService *shard.Service, publisher shard.Publisher) {
+	shards, err := shardService.CreateShards(context.Background(), consts.TIP_RANK_SOURCE, consts.SHARD_SIZE, publisher)
+	if err != nil {
+		log.Fatalf("create shards failed: %v", err)
+	}
+
+	log.Printf("create shards done, shards: %d", len(shards))
+}
+
+// publishShards splits the TIP_RANK assets into shards and writes their descriptors
+// to a file, or to stdout when no file is given
+func publishShards(shardService *shard.Service, path string) {
+	if path == "" {
+		createShards(shardService, queue.NewShardWriter(os.Stdout))
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("create shards file failed: %v", err)
+	}
+	defer f.Close()
+
+	createShards(shardService, queue.NewShardWriter(f))
+}
+
+// readShards reads the shard descriptors written by a coordinator
+func readShards(path string) []*entities.Shard {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open shards file failed: %v", err)
+	}
+	defer f.Close()
+
+	shards, err := queue.ReadShards(f)
+	if err != nil {
+		log.Fatalf("read shards failed: %v", err)
+	}
+
+	return shards
+}
+
+// printAssetProfiles writes the asset profiles scraped in a dry run to stdout
+func printAssetProfiles(profileMemory *repos.AssetProfileMemory) {
+	printJSON(profileMemory.FindAllAssetProfileModels())
+}
+
+// printJSON writes v as indented JSON to stdout
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		log.Printf("print json failed: %v", err)
+	}
 }
@@ -2,15 +2,48 @@ package config
 
 import (
 	"fmt"
-)
+	"net/url"
+	"strings"
 
-// AllowDomain const
-const AllowDomain = "ca.finance.yahoo.com"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
 
-// DomainGlob const
+// DomainGlob const matches every regional Yahoo site, so the sites share one limit rule
 const DomainGlob = "*yahoo.*"
 
-// GetAssetProfileByTickerURL get sector url
-func GetAssetProfileByTickerURL(ticker string) string {
-	return fmt.Sprintf("https://ca.finance.yahoo.com/quote/%s/profile?p=%s", ticker, ticker)
+// YahooBaseURL var
+var YahooBaseURL = "https://ca.finance.yahoo.com"
+
+// DefaultYahooRegion is the region of YahooBaseURL
+const DefaultYahooRegion = "ca"
+
+// ProfilePathTemplate is the path of the profile pages of the Yahoo sites
+const ProfilePathTemplate = "/quote/{symbol}/profile?p={symbol}"
+
+// SetYahooBaseURL points the scraper at another Yahoo host such as a fake server.
+// It must be called before the scraper is created
+func SetYahooBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid yahoo base url %q", baseURL)
+	}
+
+	YahooBaseURL = strings.TrimRight(baseURL, "/")
+
+	return nil
+}
+
+// DefaultYahooSites requests every profile page from YahooBaseURL, the site of the
+// scrapers configured without regional sites
+func DefaultYahooSites() *entities.YahooSites {
+	return &entities.YahooSites{
+		URLTemplates: map[string]string{
+			DefaultYahooRegion: YahooBaseURL + ProfilePathTemplate,
+		},
+		Regions: []string{DefaultYahooRegion},
+	}
 }
@@ -1,5 +1,12 @@
 package config
 
+import (
+	"strings"
+	"time"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
 // MongoConfig struct
 type MongoConfig struct {
 	TimeoutMS     uint64
@@ -14,7 +21,70 @@ type MongoConfig struct {
 	Colnames      map[string]string
 }
 
+// RefreshConfig struct sets how many hours an asset profile is kept before it is scraped again,
+// by default and per asset type, and how many hours a failed ticker waits before it is retried
+type RefreshConfig struct {
+	MaxAgeHours            int64
+	MaxAgeHoursByAssetType map[string]int64
+	FailedBackoffHours     int64
+}
+
+// YahooConfig struct sets the regional Yahoo sites by region, as the URL templates of their profile
+// pages, the regions tried in order for the tickers, by default, per source and per exchange,
+// and the random delay added between the requests to a site
+type YahooConfig struct {
+	Sites             map[string]string
+	Regions           []string
+	RegionsBySource   map[string][]string
+	RegionsByExchange map[string][]string
+	RandomDelayMS     uint64
+}
+
 // AppConfig struct
 type AppConfig struct {
-	Mongo MongoConfig
+	Mongo   MongoConfig
+	Refresh RefreshConfig
+	Yahoo   YahooConfig
+}
+
+// RefreshPolicy returns the refresh policy of the config
+func (c RefreshConfig) RefreshPolicy() *entities.RefreshPolicy {
+	policy := &entities.RefreshPolicy{
+		MaxAge:            time.Duration(c.MaxAgeHours) * time.Hour,
+		MaxAgeByAssetType: make(map[string]time.Duration),
+		FailedBackoff:     time.Duration(c.FailedBackoffHours) * time.Hour,
+	}
+
+	for assetType, hours := range c.MaxAgeHoursByAssetType {
+		policy.MaxAgeByAssetType[assetType] = time.Duration(hours) * time.Hour
+	}
+
+	return policy
+}
+
+// RandomDelay returns the random delay added between the requests to a Yahoo site
+func (c YahooConfig) RandomDelay() time.Duration {
+	return time.Duration(c.RandomDelayMS) * time.Millisecond
+}
+
+// YahooSites returns the regional Yahoo sites of the config
+func (c YahooConfig) YahooSites() *entities.YahooSites {
+	sites := &entities.YahooSites{
+		URLTemplates:      make(map[string]string),
+		Regions:           c.Regions,
+		RegionsBySource:   make(map[string][]string),
+		RegionsByExchange: make(map[string][]string),
+	}
+
+	for region, urlTemplate := range c.Sites {
+		sites.URLTemplates[region] = urlTemplate
+	}
+	for source, regions := range c.RegionsBySource {
+		sites.RegionsBySource[strings.ToUpper(source)] = regions
+	}
+	for exchange, regions := range c.RegionsByExchange {
+		sites.RegionsByExchange[strings.ToUpper(exchange)] = regions
+	}
+
+	return sites
 }
@@ -15,9 +15,42 @@ var AppConf = AppConfig{
 		Dbname:        "povi",
 		SchemaVersion: "1",
 		Colnames: map[string]string{
-			"yahoo_asset_profiles": "yahoo_asset_profiles",
-			"scrape_checkpoint":    "scrape_checkpoint",
-			"assets":               "assets",
+			"yahoo_asset_profiles":        "yahoo_asset_profiles",
+			"scrape_checkpoint":           "scrape_checkpoint",
+			"assets":                      "assets",
+			"failed_tickers":              "failed_tickers",
+			"scrape_runs":                 "scrape_runs",
+			"scrape_shards":               "scrape_shards",
+			"yahoo_asset_profile_history": "yahoo_asset_profile_history",
+			"yahoo_symbol_overrides":      "yahoo_symbol_overrides",
+			"yahoo_sector_mappings":       "yahoo_sector_mappings",
 		},
 	},
+	Refresh: RefreshConfig{
+		MaxAgeHours: 7 * 24,
+		MaxAgeHoursByAssetType: map[string]int64{
+			"ETF": 30 * 24,
+		},
+		FailedBackoffHours: 6,
+	},
+	Yahoo: YahooConfig{
+		Sites: map[string]string{
+			"ca":  "https://ca.finance.yahoo.com" + ProfilePathTemplate,
+			"www": "https://finance.yahoo.com" + ProfilePathTemplate,
+			"uk":  "https://uk.finance.yahoo.com" + ProfilePathTemplate,
+		},
+		Regions: []string{"ca", "www", "uk"},
+		RegionsByExchange: map[string][]string{
+			"NYSE":         {"www", "ca"},
+			"NASDAQ":       {"www", "ca"},
+			"NYSEARCA":     {"www", "ca"},
+			"NYSEAMERICAN": {"www", "ca"},
+			"LON":          {"uk", "www"},
+			"LSE":          {"uk", "www"},
+			"ETR":          {"uk", "www"},
+			"EPA":          {"uk", "www"},
+			"AMS":          {"uk", "www"},
+		},
+		RandomDelayMS: 2000,
+	},
 }
@@ -2,9 +2,15 @@ package consts
 
 // Collection names
 const (
-	ASSETS_COLLECTION               = "assets"
-	YAHOO_ASSET_PROFILES_COLLECTION = "yahoo_asset_profiles"
-	SCRAPE_CHECKPOINT_COLLECTION    = "scrape_checkpoint"
+	ASSETS_COLLECTION                      = "assets"
+	YAHOO_ASSET_PROFILES_COLLECTION        = "yahoo_asset_profiles"
+	SCRAPE_CHECKPOINT_COLLECTION           = "scrape_checkpoint"
+	FAILED_TICKERS_COLLECTION              = "failed_tickers"
+	SCRAPE_RUNS_COLLECTION                 = "scrape_runs"
+	SCRAPE_SHARDS_COLLECTION               = "scrape_shards"
+	YAHOO_ASSET_PROFILE_HISTORY_COLLECTION = "yahoo_asset_profile_history"
+	YAHOO_SYMBOL_OVERRIDES_COLLECTION      = "yahoo_symbol_overrides"
+	YAHOO_SECTOR_MAPPINGS_COLLECTION       = "yahoo_sector_mappings"
 )
 
 const (
@@ -13,4 +19,43 @@ const (
 	TIP_RANK_SOURCE = "TIP_RANK"
 )
 
+// ASSET_PROFILE_JOB is the job type of the asset profile checkpoints
+const ASSET_PROFILE_JOB = "ASSET_PROFILE"
+
 const PAGE_SIZE = 100
+
+// CHECKPOINT_LEASE_SECONDS is how long a run leases a checkpoint page before another run may
+// reclaim it, longer than the lambda timeout so only the pages of crashed runs are reclaimed
+const CHECKPOINT_LEASE_SECONDS = 20 * 60
+
+// CHECKPOINT_FRONTIER_LEASE_SECONDS is how long a run may lock the next checkpoint page
+// while it reads where the page ends
+const CHECKPOINT_FRONTIER_LEASE_SECONDS = 60
+
+// CHECKPOINT_CLAIM_ATTEMPTS is how many times a run tries to claim a page while other runs lock the next one
+const CHECKPOINT_CLAIM_ATTEMPTS = 5
+
+// CHECKPOINT_CLAIM_BACKOFF_MS is the delay before claiming again, multiplied by the attempt
+const CHECKPOINT_CLAIM_BACKOFF_MS = 200
+
+// MAX_PAGE_SIZE is the largest checkpoint page a single invocation may scrape
+const MAX_PAGE_SIZE = 1000
+
+// QUARANTINE_AFTER_FAILURES is the number of consecutive failures after which a ticker is no longer retried
+const QUARANTINE_AFTER_FAILURES = 5
+
+// DELETE_AFTER_NOT_FOUND is the number of consecutive not found scrapes after which a profile is soft deleted
+const DELETE_AFTER_NOT_FOUND = 3
+
+// FAILED_TICKERS_DRAIN_SIZE is the number of failed tickers retried with each checkpoint page
+const FAILED_TICKERS_DRAIN_SIZE = 20
+
+// SHARD_SIZE is the number of assets a fan-out worker scrapes, small enough to finish within a lambda timeout
+const SHARD_SIZE = 200
+
+// Shard statuses
+const (
+	SHARD_PENDING    = "pending"
+	SHARD_DONE       = "done"
+	SHARD_INCOMPLETE = "incomplete"
+)
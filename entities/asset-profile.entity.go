@@ -1,7 +1,27 @@
 package entities
 
+// AssetProfile struct
 type AssetProfile struct {
-	Ticker  string `json:"ticker,omitempty"`
-	Sector  string `json:"sector,omitempty"`
-	Country string `json:"country,omitempty"`
+	Ticker                string `json:"ticker,omitempty"`
+	YahooSymbol           string `json:"yahooSymbol,omitempty"`
+	YahooRegion           string `json:"yahooRegion,omitempty"`
+	Sector                string `json:"sector,omitempty"`
+	Industry              string `json:"industry,omitempty"`
+	FullTimeEmployees     int64  `json:"fullTimeEmployees,omitempty"`
+	Website               string `json:"website,omitempty"`
+	Phone                 string `json:"phone,omitempty"`
+	Street                string `json:"street,omitempty"`
+	City                  string `json:"city,omitempty"`
+	Province              string `json:"province,omitempty"`
+	PostalCode            string `json:"postalCode,omitempty"`
+	Country               string `json:"country,omitempty"`
+	CountryRaw            string `json:"countryRaw,omitempty"`
+	CountryCode           string `json:"countryCode,omitempty"`
+	CountryAlpha3         string `json:"countryAlpha3,omitempty"`
+	GicsSectorCode        string `json:"gicsSectorCode,omitempty"`
+	GicsSector            string `json:"gicsSector,omitempty"`
+	GicsIndustryGroupCode string `json:"gicsIndustryGroupCode,omitempty"`
+	GicsIndustryGroup     string `json:"gicsIndustryGroup,omitempty"`
+	GicsIndustryCode      string `json:"gicsIndustryCode,omitempty"`
+	GicsIndustry          string `json:"gicsIndustry,omitempty"`
 }
@@ -2,6 +2,7 @@ package entities
 
 // Asset struct
 type Asset struct {
+	ID               string  `json:"id,omitempty"`
 	Ticker           string  `json:"ticker,omitempty"`
 	Name             string  `json:"name,omitempty"`
 	Type             string  `json:"type,omitempty"`
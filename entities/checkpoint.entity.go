@@ -1,7 +1,16 @@
 package entities
 
-// Checkpoint struct
+// Checkpoint struct. A page holds the PageSize assets following the asset After,
+// or the first assets when After is empty. Next is the last asset of the page, which the page
+// after it follows, empty once the page is the last one. A Reclaimed page was leased by a run
+// that did not complete it, its Next is known and bounds the page
 type Checkpoint struct {
-	PageSize  int64 `json:"size,omitempty"`
-	PageIndex int64 `json:"index,omitempty"`
+	Source    string `json:"source,omitempty"`
+	JobType   string `json:"jobType,omitempty"`
+	PageSize  int64  `json:"size,omitempty"`
+	PageIndex int64  `json:"index,omitempty"`
+	After     string `json:"after,omitempty"`
+	Next      string `json:"next,omitempty"`
+	Reclaimed bool   `json:"reclaimed,omitempty"`
+	RunID     string `json:"runId,omitempty"`
 }
@@ -0,0 +1,15 @@
+package entities
+
+// FailedTicker struct
+type FailedTicker struct {
+	Ticker        string `json:"ticker,omitempty"`
+	Source        string `json:"source,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	StatusCode    int    `json:"statusCode,omitempty"`
+	Attempts      int    `json:"attempts,omitempty"`
+	FailureCount  int64  `json:"failureCount,omitempty"`
+	FirstFailedAt int64  `json:"firstFailedAt,omitempty"`
+	LastFailedAt  int64  `json:"lastFailedAt,omitempty"`
+	Quarantined   bool   `json:"quarantined,omitempty"`
+	NotAttempted  bool   `json:"notAttempted,omitempty"`
+}
@@ -0,0 +1,12 @@
+package entities
+
+import "time"
+
+// RefreshPolicy struct sets how old the profile of an asset may get before it is due for a scrape.
+// MaxAgeByAssetType overrides MaxAge for the asset types it lists, keyed by upper case type.
+// A failed ticker is due again FailedBackoff after its last failure, unless it was not attempted
+type RefreshPolicy struct {
+	MaxAge            time.Duration            `json:"maxAge,omitempty"`
+	MaxAgeByAssetType map[string]time.Duration `json:"maxAgeByAssetType,omitempty"`
+	FailedBackoff     time.Duration            `json:"failedBackoff,omitempty"`
+}
@@ -0,0 +1,33 @@
+package entities
+
+// RunReport struct summarises a scrape run
+type RunReport struct {
+	RunID               string           `json:"runId,omitempty"`
+	Source              string           `json:"source,omitempty"`
+	PageIndex           int64            `json:"pageIndex"`
+	PageSize            int64            `json:"pageSize,omitempty"`
+	Requested           int64            `json:"requested"`
+	Succeeded           int64            `json:"succeeded"`
+	Failed              int64            `json:"failed"`
+	Skipped             int64            `json:"skipped"`
+	Unchanged           int64            `json:"unchanged"`
+	NotAttempted        int64            `json:"notAttempted"`
+	ScrapedTickers      []string         `json:"scrapedTickers,omitempty"`
+	Failures            []*TickerFailure `json:"failures,omitempty"`
+	NotAttemptedTickers []string         `json:"notAttemptedTickers,omitempty"`
+	UnmappedSectors     []string         `json:"unmappedSectors,omitempty"`
+	UnmappedIndustries  []string         `json:"unmappedIndustries,omitempty"`
+	StartedAt           int64            `json:"startedAt,omitempty"`
+	FinishedAt          int64            `json:"finishedAt,omitempty"`
+	DurationMs          int64            `json:"durationMs"`
+	LatencyP50Ms        int64            `json:"latencyP50Ms"`
+	LatencyP95Ms        int64            `json:"latencyP95Ms"`
+}
+
+// TickerFailure struct is the reason a ticker failed in a scrape run
+type TickerFailure struct {
+	Ticker     string `json:"ticker,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Attempts   int    `json:"attempts,omitempty"`
+}
@@ -0,0 +1,19 @@
+package entities
+
+// SectorMapping struct maps a Yahoo sector to a GICS sector code, or a Yahoo industry
+// to a GICS industry code when the industry is set
+type SectorMapping struct {
+	YahooSector   string `json:"yahooSector,omitempty"`
+	YahooIndustry string `json:"yahooIndustry,omitempty"`
+	Code          string `json:"code,omitempty"`
+}
+
+// Classification struct is the GICS-like classification of a profile
+type Classification struct {
+	SectorCode        string `json:"sectorCode,omitempty"`
+	Sector            string `json:"sector,omitempty"`
+	IndustryGroupCode string `json:"industryGroupCode,omitempty"`
+	IndustryGroup     string `json:"industryGroup,omitempty"`
+	IndustryCode      string `json:"industryCode,omitempty"`
+	Industry          string `json:"industry,omitempty"`
+}
@@ -0,0 +1,20 @@
+package entities
+
+// Shard struct describes a slice of the assets of a source for a worker: the assets following the
+// asset After, or from the newest asset when After is empty, down to the asset Last, or to the
+// last asset of the source when Last is empty.
+// The status fields are filled in by the shard tracking once the worker reports back
+type Shard struct {
+	JobID        string `json:"jobId"`
+	Source       string `json:"source"`
+	Index        int64  `json:"index"`
+	Total        int64  `json:"total"`
+	Size         int64  `json:"size"`
+	After        string `json:"after,omitempty"`
+	Last         string `json:"last,omitempty"`
+	Status       string `json:"status,omitempty"`
+	RunID        string `json:"runId,omitempty"`
+	Succeeded    int64  `json:"succeeded,omitempty"`
+	Failed       int64  `json:"failed,omitempty"`
+	NotAttempted int64  `json:"notAttempted,omitempty"`
+}
@@ -0,0 +1,7 @@
+package entities
+
+// SymbolOverride struct maps a ticker of our assets to a Yahoo symbol the exchange rules get wrong
+type SymbolOverride struct {
+	Ticker      string `json:"ticker,omitempty"`
+	YahooSymbol string `json:"yahooSymbol,omitempty"`
+}
@@ -0,0 +1,12 @@
+package entities
+
+// YahooSites struct sets the regional Yahoo sites the profile pages are requested from.
+// URLTemplates maps the regions to the URL of their profile page, {symbol} standing for the
+// Yahoo symbol. The regions of a ticker are tried in order until one has its profile: the regions
+// of its exchange, else the regions of its source, else Regions. The keys are upper case
+type YahooSites struct {
+	URLTemplates      map[string]string   `json:"urlTemplates,omitempty"`
+	Regions           []string            `json:"regions,omitempty"`
+	RegionsBySource   map[string][]string `json:"regionsBySource,omitempty"`
+	RegionsByExchange map[string][]string `json:"regionsByExchange,omitempty"`
+}
@@ -0,0 +1,46 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// ShardMemoryQueue is a local stand-in for a message queue handing the shards out to the workers
+type ShardMemoryQueue struct {
+	mu     sync.Mutex
+	shards []*entities.Shard
+}
+
+// NewShardMemoryQueue creates new shard memory queue
+func NewShardMemoryQueue() *ShardMemoryQueue {
+	return &ShardMemoryQueue{}
+}
+
+// PublishShards queues the shards
+func (q *ShardMemoryQueue) PublishShards(ctx context.Context, shards []*entities.Shard) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, shard := range shards {
+		s := *shard
+		q.shards = append(q.shards, &s)
+	}
+
+	return nil
+}
+
+// Receive takes the next shard off the queue, false once the queue is empty
+func (q *ShardMemoryQueue) Receive() (*entities.Shard, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.shards) == 0 {
+		return nil, false
+	}
+
+	shard := q.shards[0]
+	q.shards = q.shards[1:]
+	return shard, true
+}
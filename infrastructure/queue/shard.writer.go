@@ -0,0 +1,64 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// ShardWriter publishes shard descriptors as JSON lines to a writer, such as stdout or a file
+type ShardWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewShardWriter creates new shard writer
+func NewShardWriter(w io.Writer) *ShardWriter {
+	return &ShardWriter{
+		w: w,
+	}
+}
+
+// PublishShards writes a JSON line per shard
+func (q *ShardWriter) PublishShards(ctx context.Context, shards []*entities.Shard) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	enc := json.NewEncoder(q.w)
+	for _, shard := range shards {
+		if err := enc.Encode(shard); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadShards reads the shard descriptors written by a shard writer
+func ReadShards(r io.Reader) ([]*entities.Shard, error) {
+	var shards []*entities.Shard
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var shard entities.Shard
+		if err := json.Unmarshal(line, &shard); err != nil {
+			return nil, err
+		}
+		shards = append(shards, &shard)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return shards, nil
+}
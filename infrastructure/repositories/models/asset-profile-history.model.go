@@ -0,0 +1,36 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProfileChangeModel struct is a change of a field of an asset profile, as observed by a scrape
+type ProfileChangeModel struct {
+	ID         *primitive.ObjectID `bson:"_id,omitempty"`
+	CreatedAt  int64               `bson:"createdAt,omitempty"`
+	ModifiedAt int64               `bson:"modifiedAt,omitempty"`
+	Enabled    bool                `bson:"enabled"`
+	Deleted    bool                `bson:"deleted"`
+	Schema     string              `bson:"schema,omitempty"`
+	Ticker     string              `bson:"ticker,omitempty"`
+	Field      string              `bson:"field,omitempty"`
+	OldValue   interface{}         `bson:"oldValue,omitempty"`
+	NewValue   interface{}         `bson:"newValue,omitempty"`
+	ObservedAt int64               `bson:"observedAt,omitempty"`
+}
+
+// newProfileChangeModel create profile change model
+func newProfileChangeModel(m *AssetProfileModel, field string, oldValue interface{}, newValue interface{}) *ProfileChangeModel {
+	return &ProfileChangeModel{
+		CreatedAt:  m.ModifiedAt,
+		ModifiedAt: m.ModifiedAt,
+		Enabled:    true,
+		Deleted:    false,
+		Schema:     m.Schema,
+		Ticker:     m.Ticker,
+		Field:      field,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+		ObservedAt: m.ModifiedAt,
+	}
+}
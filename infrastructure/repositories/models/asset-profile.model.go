@@ -10,26 +10,135 @@ import (
 )
 
 type AssetProfileModel struct {
-	ID         *primitive.ObjectID `bson:"_id,omitempty"`
-	CreatedAt  int64               `bson:"createdAt,omitempty"`
-	ModifiedAt int64               `bson:"modifiedAt,omitempty"`
-	Enabled    bool                `bson:"enabled"`
-	Deleted    bool                `bson:"deleted"`
-	Schema     string              `bson:"schema,omitempty"`
-	Ticker     string              `bson:"ticker,omitempty"`
-	Sector     string              `bson:"sector,omitempty"`
-	Country    string              `bson:"country,omitempty"`
+	ID                    *primitive.ObjectID `bson:"_id,omitempty"`
+	CreatedAt             int64               `bson:"createdAt,omitempty"`
+	ModifiedAt            int64               `bson:"modifiedAt,omitempty"`
+	Enabled               bool                `bson:"enabled"`
+	Deleted               bool                `bson:"deleted"`
+	Schema                string              `bson:"schema,omitempty"`
+	Ticker                string              `bson:"ticker,omitempty"`
+	YahooSymbol           string              `bson:"yahooSymbol,omitempty"`
+	YahooRegion           string              `bson:"yahooRegion,omitempty"`
+	Sector                string              `bson:"sector,omitempty"`
+	Industry              string              `bson:"industry,omitempty"`
+	FullTimeEmployees     int64               `bson:"fullTimeEmployees,omitempty"`
+	Website               string              `bson:"website,omitempty"`
+	Phone                 string              `bson:"phone,omitempty"`
+	Street                string              `bson:"street,omitempty"`
+	City                  string              `bson:"city,omitempty"`
+	Province              string              `bson:"province,omitempty"`
+	PostalCode            string              `bson:"postalCode,omitempty"`
+	Country               string              `bson:"country,omitempty"`
+	CountryRaw            string              `bson:"countryRaw,omitempty"`
+	CountryCode           string              `bson:"countryCode,omitempty"`
+	CountryAlpha3         string              `bson:"countryAlpha3,omitempty"`
+	GicsSectorCode        string              `bson:"gicsSectorCode,omitempty"`
+	GicsSector            string              `bson:"gicsSector,omitempty"`
+	GicsIndustryGroupCode string              `bson:"gicsIndustryGroupCode,omitempty"`
+	GicsIndustryGroup     string              `bson:"gicsIndustryGroup,omitempty"`
+	GicsIndustryCode      string              `bson:"gicsIndustryCode,omitempty"`
+	GicsIndustry          string              `bson:"gicsIndustry,omitempty"`
+	LastVerifiedAt        int64               `bson:"lastVerifiedAt,omitempty"`
+	NotFoundCount         int64               `bson:"notFoundCount"`
 }
 
 // NewAssetProfileModel create asset profile model
 func NewAssetProfileModel(ctx context.Context, log logger.ContextLog, assetProfile *entities.AssetProfile, schemaVersion string) (*AssetProfileModel, error) {
+	now := time.Now().UTC().Unix()
+
 	return &AssetProfileModel{
-		ModifiedAt: time.Now().UTC().Unix(),
-		Enabled:    true,
-		Deleted:    false,
-		Schema:     schemaVersion,
-		Ticker:     assetProfile.Ticker,
-		Sector:     assetProfile.Sector,
-		Country:    assetProfile.Country,
+		ModifiedAt:            now,
+		Enabled:               true,
+		Deleted:               false,
+		Schema:                schemaVersion,
+		Ticker:                assetProfile.Ticker,
+		YahooSymbol:           assetProfile.YahooSymbol,
+		YahooRegion:           assetProfile.YahooRegion,
+		Sector:                assetProfile.Sector,
+		Industry:              assetProfile.Industry,
+		FullTimeEmployees:     assetProfile.FullTimeEmployees,
+		Website:               assetProfile.Website,
+		Phone:                 assetProfile.Phone,
+		Street:                assetProfile.Street,
+		City:                  assetProfile.City,
+		Province:              assetProfile.Province,
+		PostalCode:            assetProfile.PostalCode,
+		Country:               assetProfile.Country,
+		CountryRaw:            assetProfile.CountryRaw,
+		CountryCode:           assetProfile.CountryCode,
+		CountryAlpha3:         assetProfile.CountryAlpha3,
+		GicsSectorCode:        assetProfile.GicsSectorCode,
+		GicsSector:            assetProfile.GicsSector,
+		GicsIndustryGroupCode: assetProfile.GicsIndustryGroupCode,
+		GicsIndustryGroup:     assetProfile.GicsIndustryGroup,
+		GicsIndustryCode:      assetProfile.GicsIndustryCode,
+		GicsIndustry:          assetProfile.GicsIndustry,
+		LastVerifiedAt:        now,
 	}, nil
 }
+
+// VerifiedAt returns when the profile was last confirmed by a scrape. Profiles saved
+// before the verification time was recorded fall back to their modification time
+func (m *AssetProfileModel) VerifiedAt() int64 {
+	if m.LastVerifiedAt > 0 {
+		return m.LastVerifiedAt
+	}
+	return m.ModifiedAt
+}
+
+// ChangesFrom returns the changes the model makes to the scraped fields of the stored profile.
+// Empty fields keep their stored value when upserted, so they are not changes
+func (m *AssetProfileModel) ChangesFrom(stored *AssetProfileModel) []*ProfileChangeModel {
+	var changes []*ProfileChangeModel
+
+	fields := []struct {
+		field    string
+		old, new string
+	}{
+		{"yahooSymbol", stored.YahooSymbol, m.YahooSymbol},
+		{"yahooRegion", stored.YahooRegion, m.YahooRegion},
+		{"sector", stored.Sector, m.Sector},
+		{"industry", stored.Industry, m.Industry},
+		{"website", stored.Website, m.Website},
+		{"phone", stored.Phone, m.Phone},
+		{"street", stored.Street, m.Street},
+		{"city", stored.City, m.City},
+		{"province", stored.Province, m.Province},
+		{"postalCode", stored.PostalCode, m.PostalCode},
+		{"country", stored.Country, m.Country},
+		{"countryRaw", stored.CountryRaw, m.CountryRaw},
+		{"countryCode", stored.CountryCode, m.CountryCode},
+		{"countryAlpha3", stored.CountryAlpha3, m.CountryAlpha3},
+		{"gicsSectorCode", stored.GicsSectorCode, m.GicsSectorCode},
+		{"gicsSector", stored.GicsSector, m.GicsSector},
+		{"gicsIndustryGroupCode", stored.GicsIndustryGroupCode, m.GicsIndustryGroupCode},
+		{"gicsIndustryGroup", stored.GicsIndustryGroup, m.GicsIndustryGroup},
+		{"gicsIndustryCode", stored.GicsIndustryCode, m.GicsIndustryCode},
+		{"gicsIndustry", stored.GicsIndustry, m.GicsIndustry},
+	}
+
+	for _, f := range fields {
+		if f.new != "" && f.new != f.old {
+			changes = append(changes, newProfileChangeModel(m, f.field, f.old, f.new))
+		}
+	}
+
+	if m.FullTimeEmployees != 0 && m.FullTimeEmployees != stored.FullTimeEmployees {
+		changes = append(changes, newProfileChangeModel(m, "fullTimeEmployees", stored.FullTimeEmployees, m.FullTimeEmployees))
+	}
+
+	// a scraped profile revives a soft deleted one
+	if stored.Deleted && !m.Deleted {
+		changes = append(changes, newProfileChangeModel(m, "deleted", true, false))
+	}
+
+	return changes
+}
+
+// DeletedChange returns the change soft deleting the profile at deletedAt
+func (m *AssetProfileModel) DeletedChange(deletedAt int64) *ProfileChangeModel {
+	deleted := *m
+	deleted.ModifiedAt = deletedAt
+
+	return newProfileChangeModel(&deleted, "deleted", false, true)
+}
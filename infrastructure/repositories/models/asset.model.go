@@ -0,0 +1,19 @@
+package models
+
+import (
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AssetModel struct reads an asset along with its _id, which keys the checkpoint pages
+type AssetModel struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	entities.Asset `bson:",inline"`
+}
+
+// ToEntity returns the asset with its _id as hex
+func (m *AssetModel) ToEntity() *entities.Asset {
+	asset := m.Asset
+	asset.ID = m.ID.Hex()
+	return &asset
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	logger "github.com/lenoobz/aws-lambda-logger"
@@ -16,21 +17,58 @@ type CheckPointModel struct {
 	Enabled           bool                    `bson:"enabled"`
 	Deleted           bool                    `bson:"deleted"`
 	Schema            string                  `bson:"schema,omitempty"`
+	Source            string                  `bson:"source,omitempty"`
+	JobType           string                  `bson:"jobType,omitempty"`
 	ProfileCheckPoint *ProfileCheckPointModel `bson:"profileCheckPoint,omitempty"`
 }
 
+// ProfileCheckPointModel struct. The frontier is the page after the last leased one: NextIndex,
+// and NextAfter, the _id of the asset it follows, empty to start over. A run locks the frontier
+// until FrontierExpiresAt while it reads its page, then leases the page until it commits it.
+// Leases abandoned or past their expiry are reclaimed by the next runs
 type ProfileCheckPointModel struct {
+	PageSize          int64             `bson:"size,omitempty"`
+	NextIndex         int64             `bson:"nextIndex"`
+	NextAfter         string            `bson:"nextAfter,omitempty"`
+	FrontierOwner     string            `bson:"frontierOwner,omitempty"`
+	FrontierExpiresAt int64             `bson:"frontierExpiresAt,omitempty"`
+	Leases            []*PageLeaseModel `bson:"leases,omitempty"`
+	CommittedAt       int64             `bson:"committedAt,omitempty"`
+}
+
+// LegacyCheckPointModel struct is the global checkpoint document saved before checkpoints
+// were leased page by page
+type LegacyCheckPointModel struct {
+	ProfileCheckPoint *LegacyProfileCheckPointModel `bson:"profileCheckPoint,omitempty"`
+}
+
+// LegacyProfileCheckPointModel struct. PrevIndex is the page the last run started,
+// the checkpoint was advanced before the page was scraped
+type LegacyProfileCheckPointModel struct {
 	PageSize  int64 `bson:"size,omitempty"`
 	PrevIndex int64 `bson:"prevIndex"`
 }
 
+// PageLeaseModel struct is a page leased by a run, the assets following After down to Last
+type PageLeaseModel struct {
+	Owner     string `bson:"owner"`
+	Index     int64  `bson:"index"`
+	Size      int64  `bson:"size,omitempty"`
+	After     string `bson:"after,omitempty"`
+	Last      string `bson:"last,omitempty"`
+	ClaimedAt int64  `bson:"claimedAt,omitempty"`
+	ExpiresAt int64  `bson:"expiresAt"`
+}
+
 // NewCheckPointModel create checkpoint model
-func NewCheckPointModel(ctx context.Context, log logger.ContextLog, pageSize int64, schemaVersion string) (*CheckPointModel, error) {
+func NewCheckPointModel(ctx context.Context, log logger.ContextLog, source string, jobType string, pageSize int64, schemaVersion string) (*CheckPointModel, error) {
 	return &CheckPointModel{
 		ModifiedAt: time.Now().UTC().Unix(),
 		Enabled:    true,
 		Deleted:    false,
 		Schema:     schemaVersion,
+		Source:     strings.ToUpper(source),
+		JobType:    jobType,
 		ProfileCheckPoint: &ProfileCheckPointModel{
 			PageSize: pageSize,
 		},
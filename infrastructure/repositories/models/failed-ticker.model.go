@@ -0,0 +1,76 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FailedTickerModel struct
+type FailedTickerModel struct {
+	ID            *primitive.ObjectID `bson:"_id,omitempty"`
+	CreatedAt     int64               `bson:"createdAt,omitempty"`
+	ModifiedAt    int64               `bson:"modifiedAt,omitempty"`
+	Enabled       bool                `bson:"enabled"`
+	Deleted       bool                `bson:"deleted"`
+	Schema        string              `bson:"schema,omitempty"`
+	Ticker        string              `bson:"ticker,omitempty"`
+	Source        string              `bson:"source"`
+	Reason        string              `bson:"reason,omitempty"`
+	StatusCode    int                 `bson:"statusCode,omitempty"`
+	Attempts      int                 `bson:"attempts,omitempty"`
+	FailureCount  int64               `bson:"failureCount"`
+	FirstFailedAt int64               `bson:"firstFailedAt,omitempty"`
+	LastFailedAt  int64               `bson:"lastFailedAt,omitempty"`
+	Quarantined   bool                `bson:"quarantined"`
+	QuarantinedAt int64               `bson:"quarantinedAt,omitempty"`
+	NotAttempted  bool                `bson:"notAttempted"`
+}
+
+// NewFailedTickerModel create failed ticker model
+func NewFailedTickerModel(ctx context.Context, log logger.ContextLog, failedTicker *entities.FailedTicker, schemaVersion string) (*FailedTickerModel, error) {
+	now := time.Now().UTC().Unix()
+
+	return &FailedTickerModel{
+		ModifiedAt:   now,
+		Enabled:      true,
+		Deleted:      false,
+		Schema:       schemaVersion,
+		Ticker:       failedTicker.Ticker,
+		Source:       strings.ToUpper(failedTicker.Source),
+		Reason:       failedTicker.Reason,
+		StatusCode:   failedTicker.StatusCode,
+		Attempts:     failedTicker.Attempts,
+		LastFailedAt: now,
+		NotAttempted: failedTicker.NotAttempted,
+	}, nil
+}
+
+// ToEntity converts the model to a failed ticker entity
+func (m *FailedTickerModel) ToEntity() *entities.FailedTicker {
+	return &entities.FailedTicker{
+		Ticker:        m.Ticker,
+		Source:        m.Source,
+		Reason:        m.Reason,
+		StatusCode:    m.StatusCode,
+		Attempts:      m.Attempts,
+		FailureCount:  m.FailureCount,
+		FirstFailedAt: m.FirstFailedAt,
+		LastFailedAt:  m.LastFailedAt,
+		Quarantined:   m.Quarantined,
+		NotAttempted:  m.NotAttempted,
+	}
+}
+
+// FailureIncrement is how much a failure adds to the failure count, tickers
+// that were never attempted are queued again without counting as a failure
+func (m *FailedTickerModel) FailureIncrement() int64 {
+	if m.NotAttempted {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunReportModel struct
+type RunReportModel struct {
+	ID                  *primitive.ObjectID   `bson:"_id,omitempty"`
+	CreatedAt           int64                 `bson:"createdAt,omitempty"`
+	ModifiedAt          int64                 `bson:"modifiedAt,omitempty"`
+	Enabled             bool                  `bson:"enabled"`
+	Deleted             bool                  `bson:"deleted"`
+	Schema              string                `bson:"schema,omitempty"`
+	RunID               string                `bson:"runId,omitempty"`
+	Source              string                `bson:"source,omitempty"`
+	PageIndex           int64                 `bson:"pageIndex"`
+	PageSize            int64                 `bson:"pageSize,omitempty"`
+	Requested           int64                 `bson:"requested"`
+	Succeeded           int64                 `bson:"succeeded"`
+	Failed              int64                 `bson:"failed"`
+	Skipped             int64                 `bson:"skipped"`
+	Unchanged           int64                 `bson:"unchanged"`
+	NotAttempted        int64                 `bson:"notAttempted"`
+	ScrapedTickers      []string              `bson:"scrapedTickers,omitempty"`
+	Failures            []*TickerFailureModel `bson:"failures,omitempty"`
+	NotAttemptedTickers []string              `bson:"notAttemptedTickers,omitempty"`
+	UnmappedSectors     []string              `bson:"unmappedSectors,omitempty"`
+	UnmappedIndustries  []string              `bson:"unmappedIndustries,omitempty"`
+	StartedAt           int64                 `bson:"startedAt,omitempty"`
+	FinishedAt          int64                 `bson:"finishedAt,omitempty"`
+	DurationMs          int64                 `bson:"durationMs"`
+	LatencyP50Ms        int64                 `bson:"latencyP50Ms"`
+	LatencyP95Ms        int64                 `bson:"latencyP95Ms"`
+}
+
+// TickerFailureModel struct
+type TickerFailureModel struct {
+	Ticker     string `bson:"ticker,omitempty"`
+	Reason     string `bson:"reason,omitempty"`
+	StatusCode int    `bson:"statusCode,omitempty"`
+	Attempts   int    `bson:"attempts,omitempty"`
+}
+
+// NewRunReportModel create run report model
+func NewRunReportModel(ctx context.Context, log logger.ContextLog, report *entities.RunReport, schemaVersion string) (*RunReportModel, error) {
+	now := time.Now().UTC().Unix()
+
+	var failures []*TickerFailureModel
+	for _, f := range report.Failures {
+		failures = append(failures, &TickerFailureModel{
+			Ticker:     f.Ticker,
+			Reason:     f.Reason,
+			StatusCode: f.StatusCode,
+			Attempts:   f.Attempts,
+		})
+	}
+
+	return &RunReportModel{
+		CreatedAt:           now,
+		ModifiedAt:          now,
+		Enabled:             true,
+		Deleted:             false,
+		Schema:              schemaVersion,
+		RunID:               report.RunID,
+		Source:              strings.ToUpper(report.Source),
+		PageIndex:           report.PageIndex,
+		PageSize:            report.PageSize,
+		Requested:           report.Requested,
+		Succeeded:           report.Succeeded,
+		Failed:              report.Failed,
+		Skipped:             report.Skipped,
+		Unchanged:           report.Unchanged,
+		NotAttempted:        report.NotAttempted,
+		ScrapedTickers:      report.ScrapedTickers,
+		Failures:            failures,
+		NotAttemptedTickers: report.NotAttemptedTickers,
+		UnmappedSectors:     report.UnmappedSectors,
+		UnmappedIndustries:  report.UnmappedIndustries,
+		StartedAt:           report.StartedAt,
+		FinishedAt:          report.FinishedAt,
+		DurationMs:          report.DurationMs,
+		LatencyP50Ms:        report.LatencyP50Ms,
+		LatencyP95Ms:        report.LatencyP95Ms,
+	}, nil
+}
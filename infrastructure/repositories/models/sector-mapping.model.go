@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SectorMappingModel struct
+type SectorMappingModel struct {
+	ID            *primitive.ObjectID `bson:"_id,omitempty"`
+	CreatedAt     int64               `bson:"createdAt,omitempty"`
+	ModifiedAt    int64               `bson:"modifiedAt,omitempty"`
+	Enabled       bool                `bson:"enabled"`
+	Deleted       bool                `bson:"deleted"`
+	Schema        string              `bson:"schema,omitempty"`
+	YahooSector   string              `bson:"yahooSector,omitempty"`
+	YahooIndustry string              `bson:"yahooIndustry,omitempty"`
+	Code          string              `bson:"code,omitempty"`
+}
+
+// ToEntity converts the model to a sector mapping entity
+func (m *SectorMappingModel) ToEntity() *entities.SectorMapping {
+	return &entities.SectorMapping{
+		YahooSector:   m.YahooSector,
+		YahooIndustry: m.YahooIndustry,
+		Code:          m.Code,
+	}
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShardModel struct
+type ShardModel struct {
+	ID           *primitive.ObjectID `bson:"_id,omitempty"`
+	CreatedAt    int64               `bson:"createdAt,omitempty"`
+	ModifiedAt   int64               `bson:"modifiedAt,omitempty"`
+	Enabled      bool                `bson:"enabled"`
+	Deleted      bool                `bson:"deleted"`
+	Schema       string              `bson:"schema,omitempty"`
+	JobID        string              `bson:"jobId,omitempty"`
+	Source       string              `bson:"source,omitempty"`
+	Index        int64               `bson:"index"`
+	Total        int64               `bson:"total"`
+	Size         int64               `bson:"size"`
+	After        string              `bson:"after,omitempty"`
+	Last         string              `bson:"last,omitempty"`
+	Status       string              `bson:"status,omitempty"`
+	RunID        string              `bson:"runId,omitempty"`
+	Succeeded    int64               `bson:"succeeded"`
+	Failed       int64               `bson:"failed"`
+	NotAttempted int64               `bson:"notAttempted"`
+	CompletedAt  int64               `bson:"completedAt,omitempty"`
+}
+
+// NewShardModel create shard model
+func NewShardModel(ctx context.Context, log logger.ContextLog, shard *entities.Shard, schemaVersion string) (*ShardModel, error) {
+	now := time.Now().UTC().Unix()
+
+	return &ShardModel{
+		CreatedAt:    now,
+		ModifiedAt:   now,
+		Enabled:      true,
+		Deleted:      false,
+		Schema:       schemaVersion,
+		JobID:        shard.JobID,
+		Source:       strings.ToUpper(shard.Source),
+		Index:        shard.Index,
+		Total:        shard.Total,
+		Size:         shard.Size,
+		After:        shard.After,
+		Last:         shard.Last,
+		Status:       shard.Status,
+		RunID:        shard.RunID,
+		Succeeded:    shard.Succeeded,
+		Failed:       shard.Failed,
+		NotAttempted: shard.NotAttempted,
+	}, nil
+}
+
+// ToEntity converts the model to a shard entity
+func (m *ShardModel) ToEntity() *entities.Shard {
+	return &entities.Shard{
+		JobID:        m.JobID,
+		Source:       m.Source,
+		Index:        m.Index,
+		Total:        m.Total,
+		Size:         m.Size,
+		After:        m.After,
+		Last:         m.Last,
+		Status:       m.Status,
+		RunID:        m.RunID,
+		Succeeded:    m.Succeeded,
+		Failed:       m.Failed,
+		NotAttempted: m.NotAttempted,
+	}
+}
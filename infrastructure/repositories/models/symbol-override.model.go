@@ -0,0 +1,26 @@
+package models
+
+import (
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SymbolOverrideModel struct
+type SymbolOverrideModel struct {
+	ID          *primitive.ObjectID `bson:"_id,omitempty"`
+	CreatedAt   int64               `bson:"createdAt,omitempty"`
+	ModifiedAt  int64               `bson:"modifiedAt,omitempty"`
+	Enabled     bool                `bson:"enabled"`
+	Deleted     bool                `bson:"deleted"`
+	Schema      string              `bson:"schema,omitempty"`
+	Ticker      string              `bson:"ticker,omitempty"`
+	YahooSymbol string              `bson:"yahooSymbol,omitempty"`
+}
+
+// ToEntity converts the model to a symbol override entity
+func (m *SymbolOverrideModel) ToEntity() *entities.SymbolOverride {
+	return &entities.SymbolOverride{
+		Ticker:      m.Ticker,
+		YahooSymbol: m.YahooSymbol,
+	}
+}
@@ -0,0 +1,178 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AssetProfileMemory struct is an in-memory asset profile repo. Upserts
+// merge the bson encoded model into the stored document like the $set of AssetProfileMongo
+type AssetProfileMemory struct {
+	mu       sync.RWMutex
+	log      logger.ContextLog
+	profiles map[string]bson.M
+	history  []*models.ProfileChangeModel
+}
+
+// NewAssetProfileMemory creates new asset profile memory repo
+func NewAssetProfileMemory(log logger.ContextLog) *AssetProfileMemory {
+	return &AssetProfileMemory{
+		log:      log,
+		profiles: make(map[string]bson.M),
+	}
+}
+
+// Close is a no-op kept for parity with AssetProfileMongo
+func (r *AssetProfileMemory) Close() {
+	r.log.Info(context.Background(), "close asset profile memory repo")
+}
+
+// FindAssetProfileModel returns the stored asset profile of a ticker
+func (r *AssetProfileMemory) FindAssetProfileModel(ticker string) (*models.AssetProfileModel, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	doc, ok := r.profiles[ticker]
+	if !ok {
+		return nil, false
+	}
+
+	return decodeProfileDocument(doc)
+}
+
+// FindProfileChangeModels returns the recorded changes of the profile of a ticker, oldest first
+func (r *AssetProfileMemory) FindProfileChangeModels(ticker string) []*models.ProfileChangeModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var changes []*models.ProfileChangeModel
+	for _, change := range r.history {
+		if change.Ticker == ticker {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// FindAllAssetProfileModels returns all stored asset profiles
+func (r *AssetProfileMemory) FindAllAssetProfileModels() []*models.AssetProfileModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var profiles []*models.AssetProfileModel
+	for _, doc := range r.profiles {
+		if m, ok := decodeProfileDocument(doc); ok {
+			profiles = append(profiles, m)
+		}
+	}
+
+	return profiles
+}
+
+// decodeProfileDocument decodes a stored document to an asset profile model
+func decodeProfileDocument(doc bson.M) (*models.AssetProfileModel, bool) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, false
+	}
+
+	var m models.AssetProfileModel
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return nil, false
+	}
+
+	return &m, true
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Implement interface
+///////////////////////////////////////////////////////////////////////////////
+
+// UpsertAssetProfile upsert asset profile. A profile that did not change only has its
+// verification time bumped, otherwise the changes are appended to the profile history.
+// It reports whether the stored profile was unchanged
+func (r *AssetProfileMemory) UpsertAssetProfile(ctx context.Context, assetProfile *entities.AssetProfile) (bool, error) {
+	m, err := models.NewAssetProfileModel(ctx, r.log, assetProfile, "")
+	if err != nil {
+		r.log.Error(ctx, "create model failed", "error", err)
+		return false, err
+	}
+
+	data, err := bson.Marshal(m)
+	if err != nil {
+		r.log.Error(ctx, "marshal model failed", "error", err)
+		return false, err
+	}
+
+	var set bson.M
+	if err := bson.Unmarshal(data, &set); err != nil {
+		r.log.Error(ctx, "unmarshal model failed", "error", err)
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.profiles[m.Ticker]
+	if !ok {
+		doc = bson.M{"createdAt": time.Now().UTC().Unix()}
+		r.profiles[m.Ticker] = doc
+	} else if stored, decoded := decodeProfileDocument(doc); decoded {
+		changes := m.ChangesFrom(stored)
+
+		if len(changes) == 0 {
+			doc["lastVerifiedAt"] = m.LastVerifiedAt
+			doc["notFoundCount"] = int64(0)
+			return true, nil
+		}
+
+		r.history = append(r.history, changes...)
+	}
+
+	for k, v := range set {
+		doc[k] = v
+	}
+
+	return false, nil
+}
+
+// MarkAssetProfileNotFound counts a not found scrape of the profile of a ticker, and soft deletes
+// the profile once it is not found deleteAfter times in a row. It reports whether the profile was deleted
+func (r *AssetProfileMemory) MarkAssetProfileNotFound(ctx context.Context, ticker string, deleteAfter int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.profiles[ticker]
+	if !ok {
+		return false, nil
+	}
+
+	stored, ok := decodeProfileDocument(doc)
+	if !ok {
+		return false, fmt.Errorf("cannot decode asset profile %s", ticker)
+	}
+
+	stored.NotFoundCount++
+	doc["notFoundCount"] = stored.NotFoundCount
+
+	if stored.Deleted || deleteAfter <= 0 || stored.NotFoundCount < deleteAfter {
+		return false, nil
+	}
+
+	now := time.Now().UTC().Unix()
+
+	doc["modifiedAt"] = now
+	doc["enabled"] = false
+	doc["deleted"] = true
+
+	r.history = append(r.history, stored.DeletedChange(now))
+	return true, nil
+}
@@ -91,8 +91,11 @@ func (r *AssetProfileMongo) Close() {
 // Implement interface
 ///////////////////////////////////////////////////////////////////////////////
 
-// UpsertAssetProfile upsert asset profile
-func (r *AssetProfileMongo) UpsertAssetProfile(ctx context.Context, assetProfile *entities.AssetProfile) error {
+// UpsertAssetProfile upsert asset profile. A profile that did not change only has its
+// verification time bumped, otherwise the changes are appended to the profile history.
+// The changes are taken against the document the update replaced, so concurrent upserts
+// of a ticker each record their own changes. It reports whether the stored profile was unchanged
+func (r *AssetProfileMongo) UpsertAssetProfile(ctx context.Context, assetProfile *entities.AssetProfile) (bool, error) {
 	// create new context for the query
 	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
 	defer cancel()
@@ -100,14 +103,14 @@ func (r *AssetProfileMongo) UpsertAssetProfile(ctx context.Context, assetProfile
 	m, err := models.NewAssetProfileModel(ctx, r.log, assetProfile, r.conf.SchemaVersion)
 	if err != nil {
 		r.log.Error(ctx, "create model failed", "error", err)
-		return err
+		return false, err
 	}
 
 	// what collection we are going to use
 	colname, ok := r.conf.Colnames[consts.YAHOO_ASSET_PROFILES_COLLECTION]
 	if !ok {
 		r.log.Error(ctx, "cannot find collection name")
-		return fmt.Errorf("cannot find collection name")
+		return false, fmt.Errorf("cannot find collection name")
 	}
 	col := r.db.Collection(colname)
 
@@ -116,6 +119,17 @@ func (r *AssetProfileMongo) UpsertAssetProfile(ctx context.Context, assetProfile
 		Value: m.Ticker,
 	}}
 
+	var stored models.AssetProfileModel
+	err = col.FindOne(ctx, filter).Decode(&stored)
+	if err != nil && err != mongo.ErrNoDocuments {
+		r.log.Error(ctx, "find one failed", "error", err)
+		return false, err
+	}
+
+	if err == nil && len(m.ChangesFrom(&stored)) == 0 {
+		return true, r.verifyAssetProfile(ctx, col, filter, m.LastVerifiedAt)
+	}
+
 	update := bson.D{
 		{
 			Key:   "$set",
@@ -130,13 +144,145 @@ func (r *AssetProfileMongo) UpsertAssetProfile(ctx context.Context, assetProfile
 		},
 	}
 
-	opts := options.Update().SetUpsert(true)
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var replaced models.AssetProfileModel
+	err = col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&replaced)
+
+	// an inserted profile has no history
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
 
-	_, err = col.UpdateOne(ctx, filter, update, opts)
 	if err != nil {
+		r.log.Error(ctx, "find one and update failed", "error", err)
+		return false, err
+	}
+
+	// a concurrent upsert may have made the same changes first
+	changes := m.ChangesFrom(&replaced)
+	if len(changes) == 0 {
+		return true, nil
+	}
+
+	r.log.Info(ctx, "asset profile changed", "ticker", m.Ticker, "changes", len(changes))
+	return false, r.insertProfileChanges(ctx, changes)
+}
+
+// verifyAssetProfile bumps the verification time of a profile that did not change
+func (r *AssetProfileMongo) verifyAssetProfile(ctx context.Context, col *mongo.Collection, filter bson.D, verifiedAt int64) error {
+	update := bson.D{{
+		Key: "$set",
+		Value: bson.D{
+			{Key: "lastVerifiedAt", Value: verifiedAt},
+			{Key: "notFoundCount", Value: 0},
+		},
+	}}
+
+	if _, err := col.UpdateOne(ctx, filter, update); err != nil {
 		r.log.Error(ctx, "update one failed", "error", err)
 		return err
 	}
 
 	return nil
 }
+
+// MarkAssetProfileNotFound counts a not found scrape of the profile of a ticker, and soft deletes
+// the profile once it is not found deleteAfter times in a row. It reports whether the profile was deleted
+func (r *AssetProfileMongo) MarkAssetProfileNotFound(ctx context.Context, ticker string, deleteAfter int64) (bool, error) {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	// what collection we are going to use
+	colname, ok := r.conf.Colnames[consts.YAHOO_ASSET_PROFILES_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return false, fmt.Errorf("cannot find collection name")
+	}
+	col := r.db.Collection(colname)
+
+	filter := bson.D{{
+		Key:   "ticker",
+		Value: ticker,
+	}}
+
+	update := bson.D{{
+		Key: "$inc",
+		Value: bson.D{{
+			Key:   "notFoundCount",
+			Value: 1,
+		}},
+	}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated models.AssetProfileModel
+	err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+
+	// there is no stored profile to delete
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+
+	if err != nil {
+		r.log.Error(ctx, "find one and update failed", "error", err)
+		return false, err
+	}
+
+	if updated.Deleted || deleteAfter <= 0 || updated.NotFoundCount < deleteAfter {
+		return false, nil
+	}
+
+	now := time.Now().UTC().Unix()
+
+	// only the first run reaching the threshold deletes the profile
+	deleteFilter := bson.D{
+		{Key: "_id", Value: updated.ID},
+		{Key: "deleted", Value: false},
+	}
+
+	softDelete := bson.D{{
+		Key: "$set",
+		Value: bson.D{
+			{Key: "modifiedAt", Value: now},
+			{Key: "enabled", Value: false},
+			{Key: "deleted", Value: true},
+		},
+	}}
+
+	res, err := col.UpdateOne(ctx, deleteFilter, softDelete)
+	if err != nil {
+		r.log.Error(ctx, "update one failed", "error", err)
+		return false, err
+	}
+
+	if res.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	return true, r.insertProfileChanges(ctx, []*models.ProfileChangeModel{updated.DeletedChange(now)})
+}
+
+// insertProfileChanges appends the changes of a profile to the profile history
+func (r *AssetProfileMongo) insertProfileChanges(ctx context.Context, changes []*models.ProfileChangeModel) error {
+	// what collection we are going to use
+	colname, ok := r.conf.Colnames[consts.YAHOO_ASSET_PROFILE_HISTORY_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return fmt.Errorf("cannot find collection name")
+	}
+	col := r.db.Collection(colname)
+
+	var docs []interface{}
+	for _, change := range changes {
+		docs = append(docs, change)
+	}
+
+	if _, err := col.InsertMany(ctx, docs); err != nil {
+		r.log.Error(ctx, "insert many failed", "error", err)
+		return err
+	}
+
+	return nil
+}
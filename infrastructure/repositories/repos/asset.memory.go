@@ -0,0 +1,267 @@
+package repos
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AssetMemory struct is an in-memory assets repo. Assets without an id are given a new object id,
+// and are returned newest first, sorted by id the same way AssetMongo sorts them by _id.
+// Stale assets are found by joining the profile and failed ticker repos set with JoinProfiles
+type AssetMemory struct {
+	mu            sync.RWMutex
+	log           logger.ContextLog
+	assets        map[string][]*entities.Asset
+	profiles      *AssetProfileMemory
+	failedTickers *FailedTickerMemory
+}
+
+// SeedAsset is an asset with its source as stored in a seed file
+type SeedAsset struct {
+	entities.Asset
+	Source string `json:"source"`
+}
+
+// NewAssetMemory creates new asset memory repo
+func NewAssetMemory(log logger.ContextLog) *AssetMemory {
+	return &AssetMemory{
+		log:    log,
+		assets: make(map[string][]*entities.Asset),
+	}
+}
+
+// NewAssetMemoryFromFile creates new asset memory repo seeded from a JSON array of SeedAsset
+func NewAssetMemoryFromFile(log logger.ContextLog, path string) (*AssetMemory, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var seeds []SeedAsset
+	if err := json.Unmarshal(data, &seeds); err != nil {
+		return nil, err
+	}
+
+	r := NewAssetMemory(log)
+	for i := range seeds {
+		r.AddAssets(seeds[i].Source, &seeds[i].Asset)
+	}
+
+	return r, nil
+}
+
+// AddAssets adds assets to a source
+func (r *AssetMemory) AddAssets(source string, assets ...*entities.Asset) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	uppercaseSource := strings.ToUpper(source)
+	for _, asset := range assets {
+		a := *asset
+		if a.ID == "" {
+			a.ID = primitive.NewObjectID().Hex()
+		}
+		r.assets[uppercaseSource] = append(r.assets[uppercaseSource], &a)
+	}
+}
+
+// JoinProfiles sets the repos the stale assets are joined with, like the $lookup of AssetMongo
+func (r *AssetMemory) JoinProfiles(profiles *AssetProfileMemory, failedTickers *FailedTickerMemory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.profiles = profiles
+	r.failedTickers = failedTickers
+}
+
+// Close is a no-op kept for parity with AssetMongo
+func (r *AssetMemory) Close() {
+	r.log.Info(context.Background(), "close asset memory repo")
+}
+
+// newestFirst returns copies of the assets of a source sorted by id descending, seeded assets
+// are not necessarily added in id order
+func (r *AssetMemory) newestFirst(source string) []*entities.Asset {
+	assets := r.assets[strings.ToUpper(source)]
+
+	result := make([]*entities.Asset, 0, len(assets))
+	for _, asset := range assets {
+		a := *asset
+		result = append(result, &a)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+
+	return result
+}
+
+///////////////////////////////////////////////////////////
+// Implement repo interface
+///////////////////////////////////////////////////////////
+
+// CountAssetsBySource count number of assets available
+func (r *AssetMemory) CountAssetsBySource(ctx context.Context, source string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.assets[strings.ToUpper(source)])), nil
+}
+
+// FindAllAssetsBySource find all assets by source
+func (r *AssetMemory) FindAllAssetsBySource(ctx context.Context, source string) ([]*entities.Asset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	assets := r.newestFirst(source)
+	if len(assets) == 0 {
+		return nil, nil
+	}
+
+	return assets, nil
+}
+
+// FindAssetsBySourceFromCheckpoint find the page of assets following the checkpoint asset, newest first,
+// down to the last asset of the page when it is known
+func (r *AssetMemory) FindAssetsBySourceFromCheckpoint(ctx context.Context, source string, checkpoint *entities.Checkpoint) ([]*entities.Asset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	assets := r.newestFirst(source)
+
+	// resume after the last asset of the previous page, ids of equal length compare as hex
+	start := int64(0)
+	if checkpoint.After != "" {
+		for start < int64(len(assets)) && assets[start].ID >= checkpoint.After {
+			start++
+		}
+	}
+
+	if start >= int64(len(assets)) {
+		return nil, nil
+	}
+
+	end := start + checkpoint.PageSize
+	if checkpoint.PageSize <= 0 || end > int64(len(assets)) {
+		end = int64(len(assets))
+	}
+
+	// stop at the last asset of a reclaimed page
+	if checkpoint.Next != "" {
+		for end > start && assets[end-1].ID < checkpoint.Next {
+			end--
+		}
+	}
+
+	return assets[start:end], nil
+}
+
+// FindAssetIDBySourceAt find the id of the asset at an offset of the assets of a source, newest first,
+// empty past the last asset
+func (r *AssetMemory) FindAssetIDBySourceAt(ctx context.Context, source string, offset int64) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	assets := r.newestFirst(source)
+	if offset < 0 || offset >= int64(len(assets)) {
+		return "", nil
+	}
+
+	return assets[offset].ID, nil
+}
+
+// FindStaleAssetsBySource find the assets of a source that are due for a scrape: never scraped first,
+// then failed, oldest failure first, then the oldest profiles older than the max age of their asset type.
+// Quarantined tickers and the tickers that failed within the failed backoff of the policy are left out
+func (r *AssetMemory) FindStaleAssetsBySource(ctx context.Context, source string, policy *entities.RefreshPolicy, limit int64) ([]*entities.Asset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type staleAsset struct {
+		asset       *entities.Asset
+		priority    int
+		failedAt    int64
+		refreshedAt int64
+	}
+
+	now := time.Now().UTC()
+
+	var due []staleAsset
+	for _, asset := range r.newestFirst(source) {
+		var failed *models.FailedTickerModel
+		if r.failedTickers != nil {
+			failed, _ = r.failedTickers.FindFailedTickerModel(asset.Ticker, source)
+		}
+		if failed != nil && failed.Quarantined {
+			continue
+		}
+
+		// tickers that were not attempted are not backed off
+		var failedAt int64
+		if failed != nil && !failed.NotAttempted {
+			failedAt = failed.LastFailedAt
+		}
+		if failedAt > now.Add(-policy.FailedBackoff).Unix() {
+			continue
+		}
+
+		var profile *models.AssetProfileModel
+		if r.profiles != nil {
+			profile, _ = r.profiles.FindAssetProfileModel(asset.Ticker)
+		}
+
+		switch {
+		case profile == nil:
+			due = append(due, staleAsset{asset: asset, priority: 0, failedAt: failedAt})
+		case failed != nil:
+			due = append(due, staleAsset{asset: asset, priority: 1, failedAt: failedAt, refreshedAt: profile.VerifiedAt()})
+		case profile.VerifiedAt() <= now.Add(-maxAgeOf(policy, asset.Type)).Unix():
+			due = append(due, staleAsset{asset: asset, priority: 2, refreshedAt: profile.VerifiedAt()})
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		if due[i].priority != due[j].priority {
+			return due[i].priority < due[j].priority
+		}
+		if due[i].failedAt != due[j].failedAt {
+			return due[i].failedAt < due[j].failedAt
+		}
+		if due[i].refreshedAt != due[j].refreshedAt {
+			return due[i].refreshedAt < due[j].refreshedAt
+		}
+		return due[i].asset.ID < due[j].asset.ID
+	})
+
+	if limit > 0 && int64(len(due)) > limit {
+		due = due[:limit]
+	}
+
+	var assets []*entities.Asset
+	for _, d := range due {
+		assets = append(assets, d.asset)
+	}
+
+	return assets, nil
+}
+
+// maxAgeOf returns how old the profile of an asset type may get
+func maxAgeOf(policy *entities.RefreshPolicy, assetType string) time.Duration {
+	for t, maxAge := range policy.MaxAgeByAssetType {
+		if strings.EqualFold(t, assetType) {
+			return maxAge
+		}
+	}
+	return policy.MaxAge
+}
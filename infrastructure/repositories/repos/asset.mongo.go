@@ -3,14 +3,18 @@ package repos
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	logger "github.com/lenoobz/aws-lambda-logger"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/config"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -21,6 +25,9 @@ type AssetMongo struct {
 	client *mongo.Client
 	log    logger.ContextLog
 	conf   *config.MongoConfig
+	// lookupIndexed is set once the indexes the stale assets join on exist
+	lookupIndexMu sync.Mutex
+	lookupIndexed bool
 }
 
 // NewAssetMongo creates new asset mongo repo
@@ -189,7 +196,8 @@ func (r *AssetMongo) FindAllAssetsBySource(ctx context.Context, source string) (
 	return assets, nil
 }
 
-// FindAssetsBySourceFromCheckpoint find assets from checkpoint
+// FindAssetsBySourceFromCheckpoint find the page of assets following the checkpoint asset, newest first,
+// down to the last asset of the page when it is known
 func (r *AssetMongo) FindAssetsBySourceFromCheckpoint(ctx context.Context, source string, checkpoint *entities.Checkpoint) ([]*entities.Asset, error) {
 
 	uppercaseSource := strings.ToUpper(source)
@@ -214,8 +222,35 @@ func (r *AssetMongo) FindAssetsBySourceFromCheckpoint(ctx context.Context, sourc
 		},
 	}
 
+	// resume after the last asset of the previous page
+	idRange := bson.D{}
+	if checkpoint.After != "" {
+		after, err := primitive.ObjectIDFromHex(checkpoint.After)
+		if err != nil {
+			r.log.Error(ctx, "invalid checkpoint asset id", "error", err, "after", checkpoint.After)
+			return nil, err
+		}
+
+		idRange = append(idRange, bson.E{Key: "$lt", Value: after})
+	}
+
+	// stop at the last asset of a reclaimed page
+	if checkpoint.Next != "" {
+		next, err := primitive.ObjectIDFromHex(checkpoint.Next)
+		if err != nil {
+			r.log.Error(ctx, "invalid checkpoint asset id", "error", err, "next", checkpoint.Next)
+			return nil, err
+		}
+
+		idRange = append(idRange, bson.E{Key: "$gte", Value: next})
+	}
+
+	if len(idRange) > 0 {
+		filter = append(filter, bson.E{Key: "_id", Value: idRange})
+	}
+
 	// find options
-	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetSkip(checkpoint.PageIndex * checkpoint.PageSize).SetLimit(checkpoint.PageSize)
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(checkpoint.PageSize)
 
 	cur, err := col.Find(ctx, filter, findOptions)
 
@@ -238,14 +273,240 @@ func (r *AssetMongo) FindAssetsBySourceFromCheckpoint(ctx context.Context, sourc
 
 	// iterate over the cursor to decode document one at a time
 	for cur.Next(ctx) {
-		// decode cursor to activity model
-		var asset entities.Asset
+		// decode cursor to asset model
+		var asset models.AssetModel
 		if err = cur.Decode(&asset); err != nil {
 			r.log.Error(ctx, "decode failed", "error", err)
 			return nil, err
 		}
 
-		assets = append(assets, &asset)
+		assets = append(assets, asset.ToEntity())
+	}
+
+	if err := cur.Err(); err != nil {
+		r.log.Error(ctx, "iterate over cursor failed", "error", err)
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+// FindAssetIDBySourceAt find the _id of the asset at an offset of the assets of a source, newest first,
+// empty past the last asset
+func (r *AssetMongo) FindAssetIDBySourceAt(ctx context.Context, source string, offset int64) (string, error) {
+
+	uppercaseSource := strings.ToUpper(source)
+
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	// what collection we are going to use
+	colname, ok := r.conf.Colnames[consts.ASSETS_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return "", fmt.Errorf("cannot find collection name")
+	}
+	col := r.db.Collection(colname)
+
+	// filter
+	filter := bson.D{
+		{
+			Key:   "source",
+			Value: uppercaseSource,
+		},
+	}
+
+	// find options
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}).SetSkip(offset).SetProjection(bson.D{{Key: "_id", Value: 1}})
+
+	res := col.FindOne(ctx, filter, findOptions)
+
+	err := res.Err()
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+
+	// find was not succeed
+	if err != nil {
+		r.log.Error(ctx, "find one query failed", "error", err)
+		return "", err
+	}
+
+	var asset models.AssetModel
+	if err := res.Decode(&asset); err != nil {
+		r.log.Error(ctx, "decode failed", "error", err)
+		return "", err
+	}
+
+	return asset.ID.Hex(), nil
+}
+
+// FindStaleAssetsBySource find the assets of a source that are due for a scrape, joined with their
+// profiles and failed tickers: never scraped first, then failed, oldest failure first, then the oldest
+// profiles older than the max age of their asset type. Quarantined tickers and the tickers that failed
+// within the failed backoff of the policy are left out
+func (r *AssetMongo) FindStaleAssetsBySource(ctx context.Context, source string, policy *entities.RefreshPolicy, limit int64) ([]*entities.Asset, error) {
+
+	uppercaseSource := strings.ToUpper(source)
+	now := time.Now().UTC()
+
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	// what collections we are going to use
+	colname, ok := r.conf.Colnames[consts.ASSETS_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return nil, fmt.Errorf("cannot find collection name")
+	}
+	col := r.db.Collection(colname)
+
+	profilesColname, ok := r.conf.Colnames[consts.YAHOO_ASSET_PROFILES_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return nil, fmt.Errorf("cannot find collection name")
+	}
+
+	failedColname, ok := r.conf.Colnames[consts.FAILED_TICKERS_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return nil, fmt.Errorf("cannot find collection name")
+	}
+
+	if err := r.ensureLookupIndexes(ctx, r.db.Collection(profilesColname), r.db.Collection(failedColname)); err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		// assets of the source
+		{{Key: "$match", Value: bson.D{{Key: "source", Value: uppercaseSource}}}},
+		// join the profile of the ticker
+		{{
+			Key: "$lookup",
+			Value: bson.D{
+				{Key: "from", Value: profilesColname},
+				{Key: "localField", Value: "ticker"},
+				{Key: "foreignField", Value: "ticker"},
+				{Key: "as", Value: "profiles"},
+			},
+		}},
+		// join the failed ticker of the source
+		{{
+			Key: "$lookup",
+			Value: bson.D{
+				{Key: "from", Value: failedColname},
+				{Key: "let", Value: bson.D{{Key: "ticker", Value: "$ticker"}}},
+				{Key: "pipeline", Value: bson.A{
+					bson.D{{Key: "$match", Value: bson.D{{Key: "$expr", Value: bson.D{{Key: "$and", Value: bson.A{
+						bson.D{{Key: "$eq", Value: bson.A{"$ticker", "$$ticker"}}},
+						bson.D{{Key: "$eq", Value: bson.A{"$source", uppercaseSource}}},
+					}}}}}}},
+				}},
+				{Key: "as", Value: "failures"},
+			},
+		}},
+		// priority 0 for never scraped, 1 for failed and 2 for scraped. Profiles saved before
+		// the verification time was recorded fall back to their modification time
+		{{
+			Key: "$addFields",
+			Value: bson.D{
+				{Key: "refreshedAt", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+					bson.D{{Key: "$max", Value: "$profiles.lastVerifiedAt"}},
+					bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$max", Value: "$profiles.modifiedAt"}}, 0}}},
+				}}}},
+				{Key: "quarantined", Value: bson.D{{Key: "$anyElementTrue", Value: bson.A{"$failures.quarantined"}}}},
+				// tickers that were not attempted are not backed off
+				{Key: "failedAt", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+					bson.D{{Key: "$max", Value: bson.D{{Key: "$map", Value: bson.D{
+						{Key: "input", Value: "$failures"},
+						{Key: "as", Value: "failure"},
+						{Key: "in", Value: bson.D{{Key: "$cond", Value: bson.A{"$$failure.notAttempted", 0, "$$failure.lastFailedAt"}}}},
+					}}}}},
+					0,
+				}}}},
+				{Key: "priority", Value: bson.D{{Key: "$switch", Value: bson.D{
+					{Key: "branches", Value: bson.A{
+						bson.D{
+							{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{bson.D{{Key: "$size", Value: "$profiles"}}, 0}}}},
+							{Key: "then", Value: 0},
+						},
+						bson.D{
+							{Key: "case", Value: bson.D{{Key: "$gt", Value: bson.A{bson.D{{Key: "$size", Value: "$failures"}}, 0}}}},
+							{Key: "then", Value: 1},
+						},
+					}},
+					{Key: "default", Value: 2},
+				}}}},
+				{Key: "maxAge", Value: maxAgeExpression(policy)},
+			},
+		}},
+		// scraped profiles are only due once older than their max age, failed tickers once backed off
+		{{
+			Key: "$match",
+			Value: bson.D{
+				{Key: "quarantined", Value: false},
+				{Key: "failedAt", Value: bson.D{{Key: "$lte", Value: now.Add(-policy.FailedBackoff).Unix()}}},
+				{Key: "$expr", Value: bson.D{{Key: "$or", Value: bson.A{
+					bson.D{{Key: "$lt", Value: bson.A{"$priority", 2}}},
+					bson.D{{Key: "$lte", Value: bson.A{"$refreshedAt", bson.D{{Key: "$subtract", Value: bson.A{now.Unix(), "$maxAge"}}}}}},
+				}}}},
+			},
+		}},
+		{{
+			Key: "$sort",
+			Value: bson.D{
+				{Key: "priority", Value: 1},
+				{Key: "failedAt", Value: 1},
+				{Key: "refreshedAt", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+		}},
+	}
+
+	// no limit below 1, which $limit rejects
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	pipeline = append(pipeline, bson.D{{
+		Key: "$project",
+		Value: bson.D{
+			{Key: "profiles", Value: 0},
+			{Key: "failures", Value: 0},
+		},
+	}})
+
+	cur, err := col.Aggregate(ctx, pipeline)
+
+	// only run defer function when aggregate success
+	if cur != nil {
+		defer func() {
+			if deferErr := cur.Close(ctx); deferErr != nil {
+				err = deferErr
+			}
+		}()
+	}
+
+	// aggregate was not succeed
+	if err != nil {
+		r.log.Error(ctx, "aggregate query failed", "error", err)
+		return nil, err
+	}
+
+	var assets []*entities.Asset
+
+	// iterate over the cursor to decode document one at a time
+	for cur.Next(ctx) {
+		// decode cursor to asset model
+		var asset models.AssetModel
+		if err = cur.Decode(&asset); err != nil {
+			r.log.Error(ctx, "decode failed", "error", err)
+			return nil, err
+		}
+
+		assets = append(assets, asset.ToEntity())
 	}
 
 	if err := cur.Err(); err != nil {
@@ -255,3 +516,67 @@ func (r *AssetMongo) FindAssetsBySourceFromCheckpoint(ctx context.Context, sourc
 
 	return assets, nil
 }
+
+// ensureLookupIndexes creates the indexes on the tickers of the profiles and failed tickers,
+// which the stale assets are joined with. Creating them again is a no-op, so they are
+// created once per repo
+func (r *AssetMongo) ensureLookupIndexes(ctx context.Context, profilesCol *mongo.Collection, failedCol *mongo.Collection) error {
+	r.lookupIndexMu.Lock()
+	defer r.lookupIndexMu.Unlock()
+
+	if r.lookupIndexed {
+		return nil
+	}
+
+	profilesIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "ticker", Value: 1}},
+	}
+
+	if _, err := profilesCol.Indexes().CreateOne(ctx, profilesIndex); err != nil {
+		r.log.Error(ctx, "create index failed", "error", err)
+		return err
+	}
+
+	failedIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "ticker", Value: 1},
+			{Key: "source", Value: 1},
+		},
+	}
+
+	if _, err := failedCol.Indexes().CreateOne(ctx, failedIndex); err != nil {
+		r.log.Error(ctx, "create index failed", "error", err)
+		return err
+	}
+
+	r.lookupIndexed = true
+	return nil
+}
+
+// maxAgeExpression returns the max age in seconds of the profile of an asset by its type
+func maxAgeExpression(policy *entities.RefreshPolicy) interface{} {
+	defaultMaxAge := int64(policy.MaxAge / time.Second)
+	if len(policy.MaxAgeByAssetType) == 0 {
+		return defaultMaxAge
+	}
+
+	// sorted for a stable pipeline
+	var assetTypes []string
+	for assetType := range policy.MaxAgeByAssetType {
+		assetTypes = append(assetTypes, assetType)
+	}
+	sort.Strings(assetTypes)
+
+	branches := bson.A{}
+	for _, assetType := range assetTypes {
+		branches = append(branches, bson.D{
+			{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{bson.D{{Key: "$toUpper", Value: "$type"}}, strings.ToUpper(assetType)}}}},
+			{Key: "then", Value: int64(policy.MaxAgeByAssetType[assetType] / time.Second)},
+		})
+	}
+
+	return bson.D{{Key: "$switch", Value: bson.D{
+		{Key: "branches", Value: branches},
+		{Key: "default", Value: defaultMaxAge},
+	}}}
+}
@@ -0,0 +1,173 @@
+package repos
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/checkpoint"
+)
+
+// CheckpointMemory struct is an in-memory checkpoint repo holding a checkpoint per source and job type like CheckpointMongo
+type CheckpointMemory struct {
+	mu          sync.Mutex
+	log         logger.ContextLog
+	checkpoints map[string]*models.CheckPointModel
+	clock       func() time.Time
+}
+
+// NewCheckpointMemory creates new checkpoint memory repo
+func NewCheckpointMemory(log logger.ContextLog) *CheckpointMemory {
+	return &CheckpointMemory{
+		log:         log,
+		checkpoints: make(map[string]*models.CheckPointModel),
+	}
+}
+
+// Close is a no-op kept for parity with CheckpointMongo
+func (r *CheckpointMemory) Close() {
+	r.log.Info(context.Background(), "close checkpoint memory repo")
+}
+
+// checkpointKey returns the key of the checkpoint of a source and job type
+func checkpointKey(source string, jobType string) string {
+	return strings.ToUpper(source) + "/" + jobType
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Implement interface
+///////////////////////////////////////////////////////////////////////////////
+
+// ClaimCheckpoint claims a page of the checkpoint of a source and job type for a run, reclaiming
+// an abandoned or expired page lease first, and locking the frontier page otherwise
+func (r *CheckpointMemory) ClaimCheckpoint(ctx context.Context, source string, jobType string, runID string, pageSize int64, lease time.Duration) (*entities.Checkpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := checkpointKey(source, jobType)
+
+	cp, ok := r.checkpoints[key]
+	if !ok {
+		var err error
+		cp, err = models.NewCheckPointModel(ctx, r.log, source, jobType, pageSize, "")
+		if err != nil {
+			r.log.Error(ctx, "create model failed", "error", err)
+			return nil, err
+		}
+
+		r.checkpoints[key] = cp
+	}
+
+	now := r.now()
+	pcp := cp.ProfileCheckPoint
+
+	// reclaim an abandoned or expired page lease
+	for _, l := range pcp.Leases {
+		if l.ExpiresAt <= now.Unix() {
+			l.Owner = runID
+			l.ClaimedAt = now.Unix()
+			l.ExpiresAt = now.Add(lease).Unix()
+			return toLeaseEntity(cp, l, runID), nil
+		}
+	}
+
+	// lock the frontier page unless another run is reading it
+	if pcp.FrontierOwner != "" && pcp.FrontierExpiresAt > now.Unix() {
+		return nil, checkpoint.ErrCheckpointInProgress
+	}
+
+	pcp.FrontierOwner = runID
+	pcp.FrontierExpiresAt = now.Add(consts.CHECKPOINT_FRONTIER_LEASE_SECONDS * time.Second).Unix()
+
+	return toFrontierEntity(cp, pageSize, runID), nil
+}
+
+// ReserveCheckpoint leases the frontier page locked by the run and moves the frontier past it
+func (r *CheckpointMemory) ReserveCheckpoint(ctx context.Context, source string, jobType string, page *entities.Checkpoint, lease time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cp, ok := r.checkpoints[checkpointKey(source, jobType)]
+	if !ok || cp.ProfileCheckPoint.FrontierOwner != page.RunID {
+		return checkpoint.ErrCheckpointNotClaimed
+	}
+
+	pcp := cp.ProfileCheckPoint
+	pcp.PageSize = page.PageSize
+	pcp.NextIndex = nextPageIndex(page)
+	pcp.NextAfter = page.Next
+	pcp.FrontierOwner = ""
+	pcp.FrontierExpiresAt = 0
+	pcp.Leases = append(pcp.Leases, newPageLease(page, lease, r.now()))
+
+	return nil
+}
+
+// CommitCheckpoint drops the page lease of the run, the page is done
+func (r *CheckpointMemory) CommitCheckpoint(ctx context.Context, source string, jobType string, runID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cp, ok := r.checkpoints[checkpointKey(source, jobType)]
+	if !ok || leaseOf(cp.ProfileCheckPoint, runID) == nil {
+		return checkpoint.ErrCheckpointNotClaimed
+	}
+
+	pcp := cp.ProfileCheckPoint
+
+	var leases []*models.PageLeaseModel
+	for _, l := range pcp.Leases {
+		if l.Owner != runID {
+			leases = append(leases, l)
+		}
+	}
+	pcp.Leases = leases
+	pcp.CommittedAt = r.now().Unix()
+
+	return nil
+}
+
+// AbandonCheckpoint releases the frontier lock or the page lease of the run
+func (r *CheckpointMemory) AbandonCheckpoint(ctx context.Context, source string, jobType string, runID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cp, ok := r.checkpoints[checkpointKey(source, jobType)]
+	if !ok {
+		return checkpoint.ErrCheckpointNotClaimed
+	}
+
+	pcp := cp.ProfileCheckPoint
+	released := false
+
+	if pcp.FrontierOwner == runID {
+		pcp.FrontierOwner = ""
+		pcp.FrontierExpiresAt = 0
+		released = true
+	}
+
+	if l := leaseOf(pcp, runID); l != nil {
+		l.Owner = ""
+		l.ExpiresAt = 0
+		released = true
+	}
+
+	if !released {
+		return checkpoint.ErrCheckpointNotClaimed
+	}
+
+	return nil
+}
+
+// now returns the current time, or the fake clock of the tests
+func (r *CheckpointMemory) now() time.Time {
+	if r.clock != nil {
+		return r.clock()
+	}
+	return time.Now().UTC()
+}
@@ -3,6 +3,8 @@ package repos
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	logger "github.com/lenoobz/aws-lambda-logger"
@@ -10,6 +12,8 @@ import (
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/assets"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/checkpoint"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -21,6 +25,12 @@ type CheckpointMongo struct {
 	client *mongo.Client
 	log    logger.ContextLog
 	conf   *config.MongoConfig
+	// keyIndexed is set once the unique index on the checkpoint key exists
+	keyIndexMu sync.Mutex
+	keyIndexed bool
+	// globalMigrated is set once there is no global checkpoint left to migrate
+	globalMu       sync.Mutex
+	globalMigrated bool
 }
 
 // NewCheckpointMongo creates new checkpoint mongo repo
@@ -91,102 +101,627 @@ func (r *CheckpointMongo) Close() {
 // Implement interface
 ///////////////////////////////////////////////////////////////////////////////
 
-// UpdateCheckpoint updates a checkpoint given page size and number of asset
-func (r *CheckpointMongo) UpdateCheckpoint(ctx context.Context, pageSize int64, numAssets int64) (*entities.Checkpoint, error) {
+// ClaimCheckpoint claims a page of the checkpoint of a source and job type for a run. A page leased
+// by a run that abandoned it or let its lease expire is reclaimed first, leased again until lease.
+// Otherwise the run locks the frontier page, which it reserves once it knows where the page ends.
+// Both are a single findOneAndUpdate, so concurrent runs never claim the same page
+func (r *CheckpointMongo) ClaimCheckpoint(ctx context.Context, source string, jobType string, runID string, pageSize int64, lease time.Duration) (*entities.Checkpoint, error) {
 	// create new context for the query
 	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
 	defer cancel()
 
+	col, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.ensureCheckpoint(ctx, col, source, jobType, pageSize); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	// reclaim an abandoned or expired page lease
+	filter := append(checkpointKeyFilter(source, jobType), bson.E{
+		Key: "profileCheckPoint.leases",
+		Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+			{Key: "expiresAt", Value: bson.D{{Key: "$lte", Value: now.Unix()}}},
+		}}},
+	})
+
+	update := bson.D{
+		{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "modifiedAt", Value: now.Unix()},
+				{Key: "profileCheckPoint.leases.$.owner", Value: runID},
+				{Key: "profileCheckPoint.leases.$.claimedAt", Value: now.Unix()},
+				{Key: "profileCheckPoint.leases.$.expiresAt", Value: now.Add(lease).Unix()},
+			},
+		},
+	}
+
+	cp, err := r.findOneAndUpdate(ctx, col, filter, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if cp != nil {
+		if l := leaseOf(cp.ProfileCheckPoint, runID); l != nil {
+			return toLeaseEntity(cp, l, runID), nil
+		}
+	}
+
+	// lock the frontier page unless another run is reading it
+	filter = append(checkpointKeyFilter(source, jobType), bson.E{
+		Key: "$or",
+		Value: bson.A{
+			bson.D{{Key: "profileCheckPoint.frontierOwner", Value: bson.D{{Key: "$exists", Value: false}}}},
+			bson.D{{Key: "profileCheckPoint.frontierExpiresAt", Value: bson.D{{Key: "$lte", Value: now.Unix()}}}},
+		},
+	})
+
+	update = bson.D{
+		{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "modifiedAt", Value: now.Unix()},
+				{Key: "profileCheckPoint.frontierOwner", Value: runID},
+				{Key: "profileCheckPoint.frontierExpiresAt", Value: now.Add(consts.CHECKPOINT_FRONTIER_LEASE_SECONDS * time.Second).Unix()},
+			},
+		},
+	}
+
+	cp, err = r.findOneAndUpdate(ctx, col, filter, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if cp == nil {
+		return nil, checkpoint.ErrCheckpointInProgress
+	}
+
+	return toFrontierEntity(cp, pageSize, runID), nil
+}
+
+// ReserveCheckpoint leases the frontier page locked by the run until lease, and moves the frontier
+// to the page following the last asset of the page, or back to the first page when it is the last one
+func (r *CheckpointMongo) ReserveCheckpoint(ctx context.Context, source string, jobType string, page *entities.Checkpoint, lease time.Duration) error {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	col, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	// filter
+	filter := append(checkpointKeyFilter(source, jobType), bson.E{
+		Key:   "profileCheckPoint.frontierOwner",
+		Value: page.RunID,
+	})
+
+	// update
+	update := bson.D{
+		{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "modifiedAt", Value: now.Unix()},
+				{Key: "profileCheckPoint.size", Value: page.PageSize},
+				{Key: "profileCheckPoint.nextIndex", Value: nextPageIndex(page)},
+				{Key: "profileCheckPoint.nextAfter", Value: page.Next},
+			},
+		},
+		{
+			Key: "$unset",
+			Value: bson.D{
+				{Key: "profileCheckPoint.frontierOwner", Value: ""},
+				{Key: "profileCheckPoint.frontierExpiresAt", Value: ""},
+			},
+		},
+		{
+			Key:   "$push",
+			Value: bson.D{{Key: "profileCheckPoint.leases", Value: newPageLease(page, lease, now)}},
+		},
+	}
+
+	res, err := col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.log.Error(ctx, "update one failed", "error", err)
+		return err
+	}
+
+	// the frontier lock expired and another run took it
+	if res.MatchedCount == 0 {
+		return checkpoint.ErrCheckpointNotClaimed
+	}
+
+	return nil
+}
+
+// CommitCheckpoint drops the page lease of the run, the page is done
+func (r *CheckpointMongo) CommitCheckpoint(ctx context.Context, source string, jobType string, runID string) error {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	col, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Unix()
+
+	// filter
+	filter := append(checkpointKeyFilter(source, jobType), bson.E{
+		Key:   "profileCheckPoint.leases.owner",
+		Value: runID,
+	})
+
+	// update
+	update := bson.D{
+		{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "modifiedAt", Value: now},
+				{Key: "profileCheckPoint.committedAt", Value: now},
+			},
+		},
+		{
+			Key:   "$pull",
+			Value: bson.D{{Key: "profileCheckPoint.leases", Value: bson.D{{Key: "owner", Value: runID}}}},
+		},
+	}
+
+	res, err := col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.log.Error(ctx, "update one failed", "error", err)
+		return err
+	}
+
+	// the lease expired and another run reclaimed the page
+	if res.MatchedCount == 0 {
+		return checkpoint.ErrCheckpointNotClaimed
+	}
+
+	return nil
+}
+
+// AbandonCheckpoint releases the frontier lock or the page lease of the run,
+// an abandoned page lease is reclaimed by the next claim
+func (r *CheckpointMongo) AbandonCheckpoint(ctx context.Context, source string, jobType string, runID string) error {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	col, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Unix()
+
+	// release the frontier lock
+	filter := append(checkpointKeyFilter(source, jobType), bson.E{
+		Key:   "profileCheckPoint.frontierOwner",
+		Value: runID,
+	})
+
+	update := bson.D{
+		{
+			Key:   "$set",
+			Value: bson.D{{Key: "modifiedAt", Value: now}},
+		},
+		{
+			Key: "$unset",
+			Value: bson.D{
+				{Key: "profileCheckPoint.frontierOwner", Value: ""},
+				{Key: "profileCheckPoint.frontierExpiresAt", Value: ""},
+			},
+		},
+	}
+
+	frontier, err := col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.log.Error(ctx, "update one failed", "error", err)
+		return err
+	}
+
+	// expire the page lease
+	filter = append(checkpointKeyFilter(source, jobType), bson.E{
+		Key:   "profileCheckPoint.leases.owner",
+		Value: runID,
+	})
+
+	update = bson.D{
+		{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "modifiedAt", Value: now},
+				{Key: "profileCheckPoint.leases.$.owner", Value: ""},
+				{Key: "profileCheckPoint.leases.$.expiresAt", Value: 0},
+			},
+		},
+	}
+
+	lease, err := col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.log.Error(ctx, "update one failed", "error", err)
+		return err
+	}
+
+	if frontier.MatchedCount == 0 && lease.MatchedCount == 0 {
+		return checkpoint.ErrCheckpointNotClaimed
+	}
+
+	return nil
+}
+
+// collection returns the checkpoint collection, with the unique index on the checkpoint key
+func (r *CheckpointMongo) collection(ctx context.Context) (*mongo.Collection, error) {
 	// what collection we are going to use
 	colname, ok := r.conf.Colnames[consts.SCRAPE_CHECKPOINT_COLLECTION]
 	if !ok {
 		r.log.Error(ctx, "cannot find collection name")
 		return nil, fmt.Errorf("cannot find collection name")
 	}
+
 	col := r.db.Collection(colname)
+	if err := r.ensureKeyIndex(ctx, col); err != nil {
+		return nil, err
+	}
+
+	return col, nil
+}
+
+// ensureKeyIndex creates the unique index on the source and job type of the checkpoints, which
+// keeps concurrent runs from upserting the same checkpoint twice. Creating it again is a no-op,
+// so it is created once per repo
+func (r *CheckpointMongo) ensureKeyIndex(ctx context.Context, col *mongo.Collection) error {
+	r.keyIndexMu.Lock()
+	defer r.keyIndexMu.Unlock()
+
+	if r.keyIndexed {
+		return nil
+	}
+
+	index := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "source", Value: 1},
+			{Key: "jobType", Value: 1},
+		},
+		// the global checkpoint used before checkpoints were keyed has no key
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.D{
+			{Key: "source", Value: bson.D{{Key: "$exists", Value: true}}},
+		}),
+	}
+
+	if _, err := col.Indexes().CreateOne(ctx, index); err != nil {
+		r.log.Error(ctx, "create index failed", "error", err)
+		return err
+	}
+
+	r.keyIndexed = true
+	return nil
+}
+
+// ensureCheckpoint creates the checkpoint of a source and job type when there is none yet.
+// The asset profile checkpoint of the TIP_RANK source, the only one the global checkpoint paged,
+// is not created while the global checkpoint is not migrated: the migration would then leave
+// the global checkpoint behind and the source would start over at the first page
+func (r *CheckpointMongo) ensureCheckpoint(ctx context.Context, col *mongo.Collection, source string, jobType string, pageSize int64) error {
+	if strings.ToUpper(source) == consts.TIP_RANK_SOURCE && jobType == consts.ASSET_PROFILE_JOB {
+		if err := r.ensureGlobalMigrated(ctx, col, source, jobType); err != nil {
+			return err
+		}
+	}
+
+	cp, err := models.NewCheckPointModel(ctx, r.log, source, jobType, pageSize, r.conf.SchemaVersion)
+	if err != nil {
+		r.log.Error(ctx, "create model failed", "error", err)
+		return err
+	}
 
 	// filter
-	filter := bson.D{}
+	filter := checkpointKeyFilter(source, jobType)
+
+	// update
+	update := bson.D{
+		{
+			Key: "$setOnInsert",
+			Value: bson.D{
+				{Key: "createdAt", Value: cp.ModifiedAt},
+				{Key: "modifiedAt", Value: cp.ModifiedAt},
+				{Key: "enabled", Value: cp.Enabled},
+				{Key: "deleted", Value: cp.Deleted},
+				{Key: "schema", Value: cp.Schema},
+				{Key: "profileCheckPoint", Value: cp.ProfileCheckPoint},
+			},
+		},
+	}
 
-	// find options
-	findOptions := options.FindOne()
+	opts := options.Update().SetUpsert(true)
+
+	// a concurrent run that upserted the checkpoint first makes the upsert hit the unique index
+	if _, err := col.UpdateOne(ctx, filter, update, opts); err != nil && !mongo.IsDuplicateKeyError(err) {
+		r.log.Error(ctx, "update one failed", "error", err)
+		return err
+	}
 
-	cur := col.FindOne(ctx, filter, findOptions)
+	return nil
+}
 
-	// only run defer function when find success
-	err := cur.Err()
+// ensureGlobalMigrated returns ErrGlobalCheckpointNotMigrated while the global checkpoint is there
+// and the checkpoint of the source and job type it migrates to is not. Neither is created again,
+// so once the global checkpoint is gone or migrated it is not looked up again
+func (r *CheckpointMongo) ensureGlobalMigrated(ctx context.Context, col *mongo.Collection, source string, jobType string) error {
+	r.globalMu.Lock()
+	defer r.globalMu.Unlock()
 
-	if err == mongo.ErrNoDocuments {
-		// decode cursor to activity model
-		cp, err := models.NewCheckPointModel(ctx, r.log, pageSize, r.conf.SchemaVersion)
+	if r.globalMigrated {
+		return nil
+	}
+
+	count, err := col.CountDocuments(ctx, globalCheckpointFilter())
+	if err != nil {
+		r.log.Error(ctx, "count documents failed", "error", err)
+		return err
+	}
+
+	// a global checkpoint left behind by the migration does not hold back the checkpoint it lost to
+	if count > 0 {
+		count, err = col.CountDocuments(ctx, checkpointKeyFilter(source, jobType))
 		if err != nil {
-			r.log.Error(ctx, "create model failed", "error", err)
-			return nil, err
+			r.log.Error(ctx, "count documents failed", "error", err)
+			return err
 		}
 
-		return r.updateCheckPoint(ctx, col, cp)
+		if count == 0 {
+			r.log.Error(ctx, "global checkpoint is not migrated", "source", source, "jobType", jobType)
+			return checkpoint.ErrGlobalCheckpointNotMigrated
+		}
+	}
+
+	r.globalMigrated = true
+	return nil
+}
+
+// findOneAndUpdate updates the checkpoint matching the filter and returns it updated, or nil when none matches
+func (r *CheckpointMongo) findOneAndUpdate(ctx context.Context, col *mongo.Collection, filter bson.D, update bson.D) (*models.CheckPointModel, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	res := col.FindOneAndUpdate(ctx, filter, update, opts)
+
+	err := res.Err()
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
 	}
 
-	// find was not succeed
 	if err != nil {
-		r.log.Error(ctx, "find query failed", "error", err)
+		r.log.Error(ctx, "find one and update failed", "error", err)
 		return nil, err
 	}
 
-	var checkpoint models.CheckPointModel
-	if err = cur.Decode(&checkpoint); err != nil {
+	var cp models.CheckPointModel
+	if err := res.Decode(&cp); err != nil {
 		r.log.Error(ctx, "decode failed", "error", err)
 		return nil, err
 	}
 
-	if checkpoint.ProfileCheckPoint == nil {
-		checkpoint.ProfileCheckPoint = &models.ProfileCheckPointModel{
-			PageSize:  pageSize,
-			PrevIndex: 0,
-		}
-		return r.updateCheckPoint(ctx, col, &checkpoint)
+	return &cp, nil
+}
+
+// MigrateGlobalCheckpoint moves the single checkpoint document used before checkpoints were
+// keyed by source to the key of the source and job type, unless that key already exists.
+// The prevIndex of the moved document becomes the frontier, so the source resumes at the page
+// its last run started. It reports whether a document was moved
+func (r *CheckpointMongo) MigrateGlobalCheckpoint(ctx context.Context, assetReader assets.Reader, source string, jobType string) (bool, error) {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	col, err := r.collection(ctx)
+	if err != nil {
+		return false, err
 	}
 
-	currNumAssets := checkpoint.ProfileCheckPoint.PrevIndex*checkpoint.ProfileCheckPoint.PageSize + checkpoint.ProfileCheckPoint.PageSize
-	if currNumAssets >= numAssets {
-		checkpoint.ProfileCheckPoint.PrevIndex = 0
-	} else {
-		checkpoint.ProfileCheckPoint.PrevIndex = checkpoint.ProfileCheckPoint.PrevIndex + 1
+	count, err := col.CountDocuments(ctx, checkpointKeyFilter(source, jobType))
+	if err != nil {
+		r.log.Error(ctx, "count documents failed", "error", err)
+		return false, err
 	}
 
-	checkpoint.ProfileCheckPoint.PageSize = pageSize
+	moved := false
+	if count == 0 {
+		filter := globalCheckpointFilter()
+
+		update := bson.D{
+			{
+				Key: "$set",
+				Value: bson.D{
+					{Key: "source", Value: strings.ToUpper(source)},
+					{Key: "jobType", Value: jobType},
+					{Key: "modifiedAt", Value: time.Now().UTC().Unix()},
+				},
+			},
+		}
 
-	return r.updateCheckPoint(ctx, col, &checkpoint)
+		res, err := col.UpdateOne(ctx, filter, update)
+		if err != nil {
+			r.log.Error(ctx, "update one failed", "error", err)
+			return false, err
+		}
+		moved = res.ModifiedCount > 0
+	}
+
+	if err := r.migrateFrontier(ctx, col, assetReader, source, jobType); err != nil {
+		return false, err
+	}
+
+	r.log.Info(ctx, "migrated global checkpoint", "source", source, "jobType", jobType, "moved", moved)
+	return moved, nil
 }
 
-// updateCheckPoint update checkpoint
-func (r *CheckpointMongo) updateCheckPoint(ctx context.Context, col *mongo.Collection, checkpoint *models.CheckPointModel) (*entities.Checkpoint, error) {
-	// filter
-	filter := bson.D{}
-	if checkpoint.ID != nil {
-		filter = bson.D{{Key: "_id", Value: checkpoint.ID}}
-	} else {
-		checkpoint.CreatedAt = time.Now().UTC().Unix()
+// migrateFrontier replaces the prevIndex of the checkpoint of a source and job type
+// with the frontier at that page, if the checkpoint still has one
+func (r *CheckpointMongo) migrateFrontier(ctx context.Context, col *mongo.Collection, assetReader assets.Reader, source string, jobType string) error {
+	filter := append(checkpointKeyFilter(source, jobType), bson.E{
+		Key:   "profileCheckPoint.prevIndex",
+		Value: bson.D{{Key: "$exists", Value: true}},
+	})
+
+	var legacy models.LegacyCheckPointModel
+	err := col.FindOne(ctx, filter).Decode(&legacy)
+	if err == mongo.ErrNoDocuments {
+		return nil
 	}
 
-	// update
+	if err != nil {
+		r.log.Error(ctx, "find one failed", "error", err)
+		return err
+	}
+
+	pcp, err := migrateProfileCheckPoint(ctx, assetReader, source, legacy.ProfileCheckPoint)
+	if err != nil {
+		r.log.Error(ctx, "migrate profile checkpoint failed", "error", err)
+		return err
+	}
+
+	// the frontier may wrap around to the first page, so the index is set rather than renamed
 	update := bson.D{
 		{
-			Key:   "$set",
-			Value: checkpoint,
+			Key: "$set",
+			Value: bson.D{
+				{Key: "profileCheckPoint.nextIndex", Value: pcp.NextIndex},
+				{Key: "profileCheckPoint.nextAfter", Value: pcp.NextAfter},
+				{Key: "modifiedAt", Value: time.Now().UTC().Unix()},
+			},
+		},
+		{
+			Key: "$unset",
+			Value: bson.D{
+				{Key: "profileCheckPoint.prevIndex", Value: ""},
+			},
 		},
 	}
 
-	opts := options.Update().SetUpsert(true)
+	if _, err := col.UpdateOne(ctx, filter, update); err != nil {
+		r.log.Error(ctx, "update one failed", "error", err)
+		return err
+	}
+
+	r.log.Info(ctx, "migrated checkpoint frontier", "source", source, "jobType", jobType, "nextIndex", pcp.NextIndex, "nextAfter", pcp.NextAfter)
+	return nil
+}
+
+// migrateProfileCheckPoint returns the frontier at the page the last run of a legacy checkpoint
+// started, resolving the asset the page follows. It starts over once that page is past the assets
+func migrateProfileCheckPoint(ctx context.Context, assetReader assets.Reader, source string, legacy *models.LegacyProfileCheckPointModel) (*models.ProfileCheckPointModel, error) {
+	if legacy == nil {
+		return &models.ProfileCheckPointModel{}, nil
+	}
+
+	pcp := &models.ProfileCheckPointModel{
+		PageSize: legacy.PageSize,
+	}
+	if legacy.PrevIndex <= 0 || legacy.PageSize <= 0 {
+		return pcp, nil
+	}
+
+	// the page is past the assets unless its first asset exists
+	first, err := assetReader.FindAssetIDBySourceAt(ctx, source, legacy.PrevIndex*legacy.PageSize)
+	if err != nil || first == "" {
+		return pcp, err
+	}
 
-	_, err := col.UpdateOne(ctx, filter, update, opts)
+	after, err := assetReader.FindAssetIDBySourceAt(ctx, source, legacy.PrevIndex*legacy.PageSize-1)
 	if err != nil {
-		r.log.Error(ctx, "update one failed", "error", err)
 		return nil, err
 	}
 
+	pcp.NextIndex = legacy.PrevIndex
+	pcp.NextAfter = after
+
+	return pcp, nil
+}
+
+// checkpointKeyFilter filters the checkpoint of a source and job type
+func checkpointKeyFilter(source string, jobType string) bson.D {
+	return bson.D{
+		{Key: "source", Value: strings.ToUpper(source)},
+		{Key: "jobType", Value: jobType},
+	}
+}
+
+// globalCheckpointFilter filters the global checkpoint, which has no key
+func globalCheckpointFilter() bson.D {
+	return bson.D{
+		{Key: "source", Value: bson.D{{Key: "$exists", Value: false}}},
+		{Key: "profileCheckPoint", Value: bson.D{{Key: "$exists", Value: true}}},
+	}
+}
+
+// nextPageIndex returns the number of the page after a page, back to 0 after the last page
+func nextPageIndex(page *entities.Checkpoint) int64 {
+	if page.Next == "" {
+		return 0
+	}
+	return page.PageIndex + 1
+}
+
+// newPageLease returns the lease of a reserved page for its run
+func newPageLease(page *entities.Checkpoint, lease time.Duration, now time.Time) *models.PageLeaseModel {
+	return &models.PageLeaseModel{
+		Owner:     page.RunID,
+		Index:     page.PageIndex,
+		Size:      page.PageSize,
+		After:     page.After,
+		Last:      page.Next,
+		ClaimedAt: now.Unix(),
+		ExpiresAt: now.Add(lease).Unix(),
+	}
+}
+
+// leaseOf returns the page lease of a run
+func leaseOf(pcp *models.ProfileCheckPointModel, runID string) *models.PageLeaseModel {
+	if pcp == nil {
+		return nil
+	}
+
+	for _, l := range pcp.Leases {
+		if l.Owner == runID {
+			return l
+		}
+	}
+
+	return nil
+}
+
+// toFrontierEntity returns the frontier page locked by a run
+func toFrontierEntity(cp *models.CheckPointModel, pageSize int64, runID string) *entities.Checkpoint {
 	return &entities.Checkpoint{
-		PageSize:  checkpoint.ProfileCheckPoint.PageSize,
-		PageIndex: checkpoint.ProfileCheckPoint.PrevIndex,
-	}, nil
+		Source:    cp.Source,
+		JobType:   cp.JobType,
+		PageSize:  pageSize,
+		PageIndex: cp.ProfileCheckPoint.NextIndex,
+		After:     cp.ProfileCheckPoint.NextAfter,
+		RunID:     runID,
+	}
+}
+
+// toLeaseEntity returns the page leased by a run
+func toLeaseEntity(cp *models.CheckPointModel, l *models.PageLeaseModel, runID string) *entities.Checkpoint {
+	return &entities.Checkpoint{
+		Source:    cp.Source,
+		JobType:   cp.JobType,
+		PageSize:  l.Size,
+		PageIndex: l.Index,
+		After:     l.After,
+		Next:      l.Last,
+		Reclaimed: true,
+		RunID:     runID,
+	}
 }
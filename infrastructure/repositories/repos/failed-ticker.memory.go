@@ -0,0 +1,136 @@
+package repos
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+)
+
+// FailedTickerMemory struct is an in-memory failed ticker repo
+type FailedTickerMemory struct {
+	mu            sync.Mutex
+	log           logger.ContextLog
+	failedTickers map[string]*models.FailedTickerModel
+}
+
+// NewFailedTickerMemory creates new failed ticker memory repo
+func NewFailedTickerMemory(log logger.ContextLog) *FailedTickerMemory {
+	return &FailedTickerMemory{
+		log:           log,
+		failedTickers: make(map[string]*models.FailedTickerModel),
+	}
+}
+
+// Close is a no-op kept for parity with FailedTickerMongo
+func (r *FailedTickerMemory) Close() {
+	r.log.Info(context.Background(), "close failed ticker memory repo")
+}
+
+// failedTickerKey returns the key of a ticker in a source
+func failedTickerKey(ticker string, source string) string {
+	return strings.ToUpper(source) + "/" + ticker
+}
+
+// FindFailedTickerModel returns the stored failed ticker of a ticker in a source
+func (r *FailedTickerMemory) FindFailedTickerModel(ticker string, source string) (*models.FailedTickerModel, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.failedTickers[failedTickerKey(ticker, source)]
+	if !ok {
+		return nil, false
+	}
+
+	c := *m
+	return &c, true
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Implement interface
+///////////////////////////////////////////////////////////////////////////////
+
+// FindFailedTickersBySource finds the failed tickers of a source that are not quarantined, oldest failure first
+func (r *FailedTickerMemory) FindFailedTickersBySource(ctx context.Context, source string, limit int64) ([]*entities.FailedTicker, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	uppercaseSource := strings.ToUpper(source)
+
+	var failedTickers []*entities.FailedTicker
+	for _, m := range r.failedTickers {
+		if m.Source == uppercaseSource && !m.Quarantined {
+			failedTickers = append(failedTickers, m.ToEntity())
+		}
+	}
+
+	sort.SliceStable(failedTickers, func(i, j int) bool {
+		if failedTickers[i].LastFailedAt != failedTickers[j].LastFailedAt {
+			return failedTickers[i].LastFailedAt < failedTickers[j].LastFailedAt
+		}
+		return failedTickers[i].Ticker < failedTickers[j].Ticker
+	})
+
+	if limit > 0 && int64(len(failedTickers)) > limit {
+		failedTickers = failedTickers[:limit]
+	}
+
+	return failedTickers, nil
+}
+
+// UpsertFailedTicker records a failure of a ticker and quarantines it once it has
+// failed quarantineAfter times in a row. A ticker that was not attempted keeps the
+// details of its last failure
+func (r *FailedTickerMemory) UpsertFailedTicker(ctx context.Context, failedTicker *entities.FailedTicker, quarantineAfter int64) (*entities.FailedTicker, error) {
+	m, err := models.NewFailedTickerModel(ctx, r.log, failedTicker, "")
+	if err != nil {
+		r.log.Error(ctx, "create model failed", "error", err)
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := failedTickerKey(m.Ticker, m.Source)
+
+	stored, ok := r.failedTickers[key]
+	if !ok {
+		stored = m
+		stored.CreatedAt = m.LastFailedAt
+		stored.FirstFailedAt = m.LastFailedAt
+		r.failedTickers[key] = stored
+	} else {
+		stored.ModifiedAt = m.ModifiedAt
+		stored.NotAttempted = m.NotAttempted
+
+		if !m.NotAttempted {
+			stored.Reason = m.Reason
+			stored.StatusCode = m.StatusCode
+			stored.Attempts = m.Attempts
+			stored.LastFailedAt = m.LastFailedAt
+		}
+	}
+
+	stored.FailureCount += m.FailureIncrement()
+
+	if !stored.Quarantined && quarantineAfter > 0 && stored.FailureCount >= quarantineAfter {
+		stored.Quarantined = true
+		stored.QuarantinedAt = time.Now().UTC().Unix()
+	}
+
+	return stored.ToEntity(), nil
+}
+
+// DeleteFailedTicker removes a ticker from the failed tickers
+func (r *FailedTickerMemory) DeleteFailedTicker(ctx context.Context, ticker string, source string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failedTickers, failedTickerKey(ticker, source))
+	return nil
+}
@@ -0,0 +1,302 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/config"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FailedTickerMongo struct
+type FailedTickerMongo struct {
+	db     *mongo.Database
+	client *mongo.Client
+	log    logger.ContextLog
+	conf   *config.MongoConfig
+}
+
+// NewFailedTickerMongo creates new failed ticker mongo repo
+func NewFailedTickerMongo(db *mongo.Database, log logger.ContextLog, conf *config.MongoConfig) (*FailedTickerMongo, error) {
+	if db != nil {
+		return &FailedTickerMongo{
+			db:   db,
+			log:  log,
+			conf: conf,
+		}, nil
+	}
+
+	// set context with timeout from the config
+	// create new context for the query
+	ctx, cancel := createContext(context.Background(), conf.TimeoutMS)
+	defer cancel()
+
+	// set mongo client options
+	clientOptions := options.Client()
+
+	// set min pool size
+	if conf.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(conf.MinPoolSize)
+	}
+
+	// set max pool size
+	if conf.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(conf.MaxPoolSize)
+	}
+
+	// set max idle time ms
+	if conf.MaxIdleTimeMS > 0 {
+		clientOptions.SetMaxConnIdleTime(time.Duration(conf.MaxIdleTimeMS) * time.Millisecond)
+	}
+
+	// construct a connection string from mongo config object
+	cxnString := fmt.Sprintf("mongodb+srv://%s:%s@%s", conf.Username, conf.Password, conf.Host)
+
+	// create mongo client by making new connection
+	client, err := mongo.Connect(ctx, clientOptions.ApplyURI(cxnString))
+	if err != nil {
+		return nil, err
+	}
+
+	return &FailedTickerMongo{
+		db:     client.Database(conf.Dbname),
+		client: client,
+		log:    log,
+		conf:   conf,
+	}, nil
+}
+
+// Close disconnect from database
+func (r *FailedTickerMongo) Close() {
+	ctx := context.Background()
+	r.log.Info(ctx, "close mongo client")
+
+	if r.client == nil {
+		return
+	}
+
+	if err := r.client.Disconnect(ctx); err != nil {
+		r.log.Error(ctx, "disconnect mongo failed", "error", err)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Implement interface
+///////////////////////////////////////////////////////////////////////////////
+
+// FindFailedTickersBySource finds the failed tickers of a source that are not quarantined, oldest failure first
+func (r *FailedTickerMongo) FindFailedTickersBySource(ctx context.Context, source string, limit int64) ([]*entities.FailedTicker, error) {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	// what collection we are going to use
+	colname, ok := r.conf.Colnames[consts.FAILED_TICKERS_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return nil, fmt.Errorf("cannot find collection name")
+	}
+	col := r.db.Collection(colname)
+
+	// filter
+	filter := bson.D{
+		{
+			Key:   "source",
+			Value: strings.ToUpper(source),
+		},
+		{
+			Key:   "quarantined",
+			Value: false,
+		},
+	}
+
+	// find options
+	findOptions := options.Find().SetSort(bson.D{{Key: "lastFailedAt", Value: 1}})
+	if limit > 0 {
+		findOptions.SetLimit(limit)
+	}
+
+	cur, err := col.Find(ctx, filter, findOptions)
+
+	// only run defer function when find success
+	if cur != nil {
+		defer func() {
+			if deferErr := cur.Close(ctx); deferErr != nil {
+				err = deferErr
+			}
+		}()
+	}
+
+	// find was not succeed
+	if err != nil {
+		r.log.Error(ctx, "find query failed", "error", err)
+		return nil, err
+	}
+
+	var failedTickers []*entities.FailedTicker
+
+	// iterate over the cursor to decode document one at a time
+	for cur.Next(ctx) {
+		var m models.FailedTickerModel
+		if err = cur.Decode(&m); err != nil {
+			r.log.Error(ctx, "decode failed", "error", err)
+			return nil, err
+		}
+
+		failedTickers = append(failedTickers, m.ToEntity())
+	}
+
+	if err := cur.Err(); err != nil {
+		r.log.Error(ctx, "iterate over cursor failed", "error", err)
+		return nil, err
+	}
+
+	return failedTickers, nil
+}
+
+// UpsertFailedTicker records a failure of a ticker and quarantines it once it has
+// failed quarantineAfter times in a row. A ticker that was not attempted keeps the
+// details of its last failure
+func (r *FailedTickerMongo) UpsertFailedTicker(ctx context.Context, failedTicker *entities.FailedTicker, quarantineAfter int64) (*entities.FailedTicker, error) {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	m, err := models.NewFailedTickerModel(ctx, r.log, failedTicker, r.conf.SchemaVersion)
+	if err != nil {
+		r.log.Error(ctx, "create model failed", "error", err)
+		return nil, err
+	}
+
+	// what collection we are going to use
+	colname, ok := r.conf.Colnames[consts.FAILED_TICKERS_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return nil, fmt.Errorf("cannot find collection name")
+	}
+	col := r.db.Collection(colname)
+
+	filter := bson.D{
+		{
+			Key:   "ticker",
+			Value: m.Ticker,
+		},
+		{
+			Key:   "source",
+			Value: m.Source,
+		},
+	}
+
+	// the failure details are set on insert only when the ticker was not attempted,
+	// so a run stopped before the deadline keeps the last real failure of the ticker
+	set := bson.D{
+		{Key: "modifiedAt", Value: m.ModifiedAt},
+		{Key: "notAttempted", Value: m.NotAttempted},
+	}
+	failure := bson.D{
+		{Key: "enabled", Value: m.Enabled},
+		{Key: "deleted", Value: m.Deleted},
+		{Key: "schema", Value: m.Schema},
+		{Key: "reason", Value: m.Reason},
+		{Key: "statusCode", Value: m.StatusCode},
+		{Key: "attempts", Value: m.Attempts},
+		{Key: "lastFailedAt", Value: m.LastFailedAt},
+	}
+	setOnInsert := bson.D{
+		{Key: "createdAt", Value: m.LastFailedAt},
+		{Key: "firstFailedAt", Value: m.LastFailedAt},
+		{Key: "quarantined", Value: false},
+	}
+
+	if m.NotAttempted {
+		setOnInsert = append(setOnInsert, failure...)
+	} else {
+		set = append(set, failure...)
+	}
+
+	update := bson.D{
+		{
+			Key:   "$set",
+			Value: set,
+		},
+		{
+			Key:   "$setOnInsert",
+			Value: setOnInsert,
+		},
+		{
+			Key: "$inc",
+			Value: bson.D{
+				{Key: "failureCount", Value: m.FailureIncrement()},
+			},
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var updated models.FailedTickerModel
+	if err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		r.log.Error(ctx, "find one and update failed", "error", err)
+		return nil, err
+	}
+
+	if !updated.Quarantined && quarantineAfter > 0 && updated.FailureCount >= quarantineAfter {
+		updated.Quarantined = true
+		updated.QuarantinedAt = time.Now().UTC().Unix()
+
+		quarantine := bson.D{{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "quarantined", Value: updated.Quarantined},
+				{Key: "quarantinedAt", Value: updated.QuarantinedAt},
+			},
+		}}
+
+		if _, err := col.UpdateOne(ctx, bson.D{{Key: "_id", Value: updated.ID}}, quarantine); err != nil {
+			r.log.Error(ctx, "update one failed", "error", err)
+			return nil, err
+		}
+	}
+
+	return updated.ToEntity(), nil
+}
+
+// DeleteFailedTicker removes a ticker from the failed tickers
+func (r *FailedTickerMongo) DeleteFailedTicker(ctx context.Context, ticker string, source string) error {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	// what collection we are going to use
+	colname, ok := r.conf.Colnames[consts.FAILED_TICKERS_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return fmt.Errorf("cannot find collection name")
+	}
+	col := r.db.Collection(colname)
+
+	filter := bson.D{
+		{
+			Key:   "ticker",
+			Value: ticker,
+		},
+		{
+			Key:   "source",
+			Value: strings.ToUpper(source),
+		},
+	}
+
+	if _, err := col.DeleteOne(ctx, filter); err != nil {
+		r.log.Error(ctx, "delete one failed", "error", err)
+		return err
+	}
+
+	return nil
+}
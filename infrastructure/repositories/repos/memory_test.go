@@ -0,0 +1,551 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/checkpoint"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func newTestLogger(t *testing.T) logger.ContextLog {
+	t.Helper()
+
+	zap, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	t.Cleanup(func() { zap.Close() })
+
+	return zap
+}
+
+func tickersOf(assets []*entities.Asset) []string {
+	var tickers []string
+	for _, asset := range assets {
+		tickers = append(tickers, asset.Ticker)
+	}
+	return tickers
+}
+
+func TestAssetMemoryUppercasesSourceAndPagesNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	r := NewAssetMemory(newTestLogger(t))
+
+	for _, ticker := range []string{"A", "B", "C"} {
+		r.AddAssets("tip_rank", &entities.Asset{Ticker: ticker})
+	}
+
+	count, _ := r.CountAssetsBySource(ctx, "Tip_Rank")
+	if count != 3 {
+		t.Errorf("count = %d, want 3", count)
+	}
+
+	all, _ := r.FindAllAssetsBySource(ctx, "TIP_RANK")
+	if got, want := tickersOf(all), []string{"C", "B", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("all assets = %v, want %v", got, want)
+	}
+
+	page, _ := r.FindAssetsBySourceFromCheckpoint(ctx, "tip_rank", &entities.Checkpoint{PageSize: 2, After: all[1].ID})
+	if got, want := tickersOf(page), []string{"A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("page after B = %v, want %v", got, want)
+	}
+}
+
+func TestAssetMemorySortsSeededAssetsByID(t *testing.T) {
+	ctx := context.Background()
+	r := NewAssetMemory(newTestLogger(t))
+
+	// seeded out of id order, the way a seed file exported without a sort lists them
+	r.AddAssets("tip_rank",
+		&entities.Asset{ID: "5f1b2c3d4e5f6a7b8c9d0e02", Ticker: "B"},
+		&entities.Asset{ID: "5f1b2c3d4e5f6a7b8c9d0e03", Ticker: "C"},
+		&entities.Asset{ID: "5f1b2c3d4e5f6a7b8c9d0e01", Ticker: "A"},
+	)
+	r.AddAssets("tip_rank", &entities.Asset{Ticker: "NEW"})
+
+	all, _ := r.FindAllAssetsBySource(ctx, "tip_rank")
+	if got, want := tickersOf(all), []string{"NEW", "C", "B", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("all assets = %v, want %v", got, want)
+	}
+
+	if id, _ := r.FindAssetIDBySourceAt(ctx, "tip_rank", 2); id != "5f1b2c3d4e5f6a7b8c9d0e02" {
+		t.Errorf("id at 2 = %q, want the id of B", id)
+	}
+
+	page, _ := r.FindAssetsBySourceFromCheckpoint(ctx, "tip_rank", &entities.Checkpoint{PageSize: 2, After: "5f1b2c3d4e5f6a7b8c9d0e03"})
+	if got, want := tickersOf(page), []string{"B", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("page after C = %v, want %v", got, want)
+	}
+}
+
+func TestAssetMemoryPagesDoNotShiftOnInsert(t *testing.T) {
+	ctx := context.Background()
+	r := NewAssetMemory(newTestLogger(t))
+
+	for _, ticker := range []string{"A", "B", "C", "D"} {
+		r.AddAssets("tip_rank", &entities.Asset{Ticker: ticker})
+	}
+
+	first, _ := r.FindAssetsBySourceFromCheckpoint(ctx, "tip_rank", &entities.Checkpoint{PageSize: 2})
+	if got, want := tickersOf(first), []string{"D", "C"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("first page = %v, want %v", got, want)
+	}
+
+	// a newer asset goes before the first page instead of pushing the next page down
+	r.AddAssets("tip_rank", &entities.Asset{Ticker: "E"})
+
+	second, _ := r.FindAssetsBySourceFromCheckpoint(ctx, "tip_rank", &entities.Checkpoint{PageSize: 2, After: first[1].ID})
+	if got, want := tickersOf(second), []string{"B", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second page = %v, want %v", got, want)
+	}
+}
+
+func TestAssetMemoryFindsStaleAssetsByPriority(t *testing.T) {
+	ctx := context.Background()
+	r := NewAssetMemory(newTestLogger(t))
+	profiles := NewAssetProfileMemory(newTestLogger(t))
+	failedTickers := NewFailedTickerMemory(newTestLogger(t))
+	r.JoinProfiles(profiles, failedTickers)
+
+	r.AddAssets("tip_rank",
+		&entities.Asset{Ticker: "STOCK", Type: "STOCK"},
+		&entities.Asset{Ticker: "ETF", Type: "etf"},
+		&entities.Asset{Ticker: "FAILED", Type: "STOCK"},
+		&entities.Asset{Ticker: "GONE", Type: "STOCK"},
+		&entities.Asset{Ticker: "NEW", Type: "STOCK"},
+	)
+
+	for _, ticker := range []string{"STOCK", "ETF", "FAILED"} {
+		profiles.UpsertAssetProfile(ctx, &entities.AssetProfile{Ticker: ticker})
+	}
+	failedTickers.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "FAILED", Source: "TIP_RANK"}, 3)
+	for i := 0; i < 3; i++ {
+		failedTickers.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "GONE", Source: "TIP_RANK", StatusCode: 404}, 3)
+	}
+
+	// only the ETF profiles are old enough to refresh, and quarantined tickers are left out
+	policy := &entities.RefreshPolicy{MaxAge: time.Hour, MaxAgeByAssetType: map[string]time.Duration{"ETF": 0}}
+
+	stale, _ := r.FindStaleAssetsBySource(ctx, "Tip_Rank", policy, 0)
+	if got, want := tickersOf(stale), []string{"NEW", "FAILED", "ETF"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stale assets = %v, want %v", got, want)
+	}
+
+	stale, _ = r.FindStaleAssetsBySource(ctx, "TIP_RANK", policy, 2)
+	if got, want := tickersOf(stale), []string{"NEW", "FAILED"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stale assets = %v, want %v", got, want)
+	}
+}
+
+func TestAssetMemoryBacksOffFailedTickers(t *testing.T) {
+	ctx := context.Background()
+	r := NewAssetMemory(newTestLogger(t))
+	profiles := NewAssetProfileMemory(newTestLogger(t))
+	failedTickers := NewFailedTickerMemory(newTestLogger(t))
+	r.JoinProfiles(profiles, failedTickers)
+
+	tickers := []string{"RECENT", "OLD", "OLDER", "QUEUED", "NEW_RECENT"}
+	for _, ticker := range tickers {
+		r.AddAssets("tip_rank", &entities.Asset{Ticker: ticker})
+	}
+
+	for _, ticker := range tickers[:4] {
+		profiles.UpsertAssetProfile(ctx, &entities.AssetProfile{Ticker: ticker})
+	}
+	for _, ticker := range []string{"RECENT", "OLD", "OLDER", "NEW_RECENT"} {
+		failedTickers.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: ticker, Source: "TIP_RANK"}, 3)
+	}
+	failedTickers.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "QUEUED", Source: "TIP_RANK", NotAttempted: true}, 3)
+
+	// every ticker was recorded now, move the failures of OLD and OLDER past the backoff
+	now := time.Now().UTC()
+	failedTickers.failedTickers[failedTickerKey("OLD", "TIP_RANK")].LastFailedAt = now.Add(-2 * time.Hour).Unix()
+	failedTickers.failedTickers[failedTickerKey("OLDER", "TIP_RANK")].LastFailedAt = now.Add(-3 * time.Hour).Unix()
+
+	// the tickers that failed within the hour are left out, whether scraped or not, unless not attempted
+	policy := &entities.RefreshPolicy{MaxAge: time.Hour, FailedBackoff: time.Hour}
+
+	stale, _ := r.FindStaleAssetsBySource(ctx, "tip_rank", policy, 0)
+	if got, want := tickersOf(stale), []string{"QUEUED", "OLDER", "OLD"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stale assets = %v, want %v", got, want)
+	}
+
+	// without a backoff every failed ticker is due again
+	stale, _ = r.FindStaleAssetsBySource(ctx, "tip_rank", &entities.RefreshPolicy{MaxAge: time.Hour}, 0)
+	if got, want := len(stale), len(tickers); got != want {
+		t.Errorf("stale assets = %v, want all %d", tickersOf(stale), want)
+	}
+}
+
+func TestAssetMemoryFindsAssetIDsByOffset(t *testing.T) {
+	ctx := context.Background()
+	r := NewAssetMemory(newTestLogger(t))
+
+	for _, ticker := range []string{"A", "B", "C"} {
+		r.AddAssets("tip_rank", &entities.Asset{Ticker: ticker})
+	}
+	all, _ := r.FindAllAssetsBySource(ctx, "TIP_RANK")
+
+	for offset, asset := range all {
+		if id, _ := r.FindAssetIDBySourceAt(ctx, "tip_rank", int64(offset)); id != asset.ID {
+			t.Errorf("id at %d = %q, want %q", offset, id, asset.ID)
+		}
+	}
+
+	if id, _ := r.FindAssetIDBySourceAt(ctx, "tip_rank", 3); id != "" {
+		t.Errorf("id past the last asset = %q, want empty", id)
+	}
+
+	// a shard from the asset after C down to the last asset
+	shard, _ := r.FindAssetsBySourceFromCheckpoint(ctx, "tip_rank", &entities.Checkpoint{After: all[0].ID, Next: all[1].ID})
+	if got, want := tickersOf(shard), []string{"B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shard after C to B = %v, want %v", got, want)
+	}
+}
+
+// claimPage claims a checkpoint page for a run and reserves a frontier page ending at next
+func claimPage(ctx context.Context, r *CheckpointMemory, source string, runID string, next string) (*entities.Checkpoint, error) {
+	cp, err := r.ClaimCheckpoint(ctx, source, consts.ASSET_PROFILE_JOB, runID, 10, time.Minute)
+	if err != nil || cp.Reclaimed {
+		return cp, err
+	}
+
+	cp.Next = next
+	return cp, r.ReserveCheckpoint(ctx, source, consts.ASSET_PROFILE_JOB, cp, time.Minute)
+}
+
+func TestCheckpointMemoryWrapsAround(t *testing.T) {
+	ctx := context.Background()
+	r := NewCheckpointMemory(newTestLogger(t))
+
+	// the third page is the last one, it has no next asset
+	nexts := []string{"j", "t", "", "j", "t"}
+
+	var indexes []int64
+	var afters []string
+	for i, next := range nexts {
+		runID := fmt.Sprintf("run-%d", i)
+
+		cp, err := claimPage(ctx, r, "tip_rank", runID, next)
+		if err != nil {
+			t.Fatalf("claim checkpoint: %v", err)
+		}
+		indexes = append(indexes, cp.PageIndex)
+		afters = append(afters, cp.After)
+
+		if err := r.CommitCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, runID); err != nil {
+			t.Fatalf("commit checkpoint: %v", err)
+		}
+	}
+
+	if want := []int64{0, 1, 2, 0, 1}; !reflect.DeepEqual(indexes, want) {
+		t.Errorf("page indexes = %v, want %v", indexes, want)
+	}
+	if want := []string{"", "j", "t", "", "j"}; !reflect.DeepEqual(afters, want) {
+		t.Errorf("page afters = %q, want %q", afters, want)
+	}
+}
+
+func TestMigratedCheckpointResumesAtTheLegacyPage(t *testing.T) {
+	ctx := context.Background()
+	assetRepo := NewAssetMemory(newTestLogger(t))
+	checkpointRepo := NewCheckpointMemory(newTestLogger(t))
+
+	for _, ticker := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+		assetRepo.AddAssets("tip_rank", &entities.Asset{Ticker: ticker})
+	}
+
+	// the legacy checkpoint last started the third page, it skipped 4 assets
+	doc, err := bson.Marshal(bson.M{
+		"createdAt":         int64(1600000000),
+		"enabled":           true,
+		"profileCheckPoint": bson.M{"size": int64(2), "prevIndex": int64(2)},
+	})
+	if err != nil {
+		t.Fatalf("marshal legacy checkpoint: %v", err)
+	}
+
+	var legacy models.LegacyCheckPointModel
+	if err := bson.Unmarshal(doc, &legacy); err != nil {
+		t.Fatalf("unmarshal legacy checkpoint: %v", err)
+	}
+
+	pcp, err := migrateProfileCheckPoint(ctx, assetRepo, "tip_rank", legacy.ProfileCheckPoint)
+	if err != nil {
+		t.Fatalf("migrate profile checkpoint: %v", err)
+	}
+
+	checkpointRepo.checkpoints[checkpointKey("tip_rank", consts.ASSET_PROFILE_JOB)] = &models.CheckPointModel{
+		Source:            "TIP_RANK",
+		JobType:           consts.ASSET_PROFILE_JOB,
+		ProfileCheckPoint: pcp,
+	}
+
+	cp, err := checkpointRepo.ClaimCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, "run", 2, time.Minute)
+	if err != nil || cp.PageIndex != 2 {
+		t.Fatalf("claim checkpoint = %+v, %v, want page 2", cp, err)
+	}
+
+	page, _ := assetRepo.FindAssetsBySourceFromCheckpoint(ctx, "tip_rank", cp)
+	if got, want := tickersOf(page), []string{"C", "B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("page = %v, want %v", got, want)
+	}
+
+	// a legacy page past the assets starts over
+	pcp, _ = migrateProfileCheckPoint(ctx, assetRepo, "tip_rank", &models.LegacyProfileCheckPointModel{PageSize: 2, PrevIndex: 4})
+	if pcp.NextIndex != 0 || pcp.NextAfter != "" {
+		t.Errorf("profile checkpoint past the assets = %+v, want the first page", pcp)
+	}
+}
+
+func TestCheckpointMemoryLeasesDistinctPages(t *testing.T) {
+	ctx := context.Background()
+	r := NewCheckpointMemory(newTestLogger(t))
+
+	now := time.Unix(1600000000, 0)
+	r.clock = func() time.Time { return now }
+
+	// the frontier page is locked until its run reserves it
+	frontier, err := r.ClaimCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, "a", 10, time.Minute)
+	if err != nil || frontier.PageIndex != 0 || frontier.Reclaimed {
+		t.Fatalf("claim a = %+v, %v, want frontier page 0", frontier, err)
+	}
+	if _, err := r.ClaimCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, "b", 10, time.Minute); !errors.Is(err, checkpoint.ErrCheckpointInProgress) {
+		t.Errorf("claim b = %v, want ErrCheckpointInProgress", err)
+	}
+
+	frontier.Next = "j"
+	if err := r.ReserveCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, frontier, time.Minute); err != nil {
+		t.Fatalf("reserve a: %v", err)
+	}
+
+	// concurrent runs lease the pages after it
+	b, err := claimPage(ctx, r, "tip_rank", "b", "t")
+	if err != nil || b.PageIndex != 1 || b.After != "j" {
+		t.Fatalf("claim b = %+v, %v, want page 1 after j", b, err)
+	}
+
+	if err := r.CommitCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, "c"); !errors.Is(err, checkpoint.ErrCheckpointNotClaimed) {
+		t.Errorf("commit c = %v, want ErrCheckpointNotClaimed", err)
+	}
+
+	// an abandoned page is reclaimed as is before the next page
+	if err := r.AbandonCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, "b"); err != nil {
+		t.Fatalf("abandon b: %v", err)
+	}
+	c, err := claimPage(ctx, r, "tip_rank", "c", "")
+	if err != nil || !c.Reclaimed || c.PageIndex != 1 || c.After != "j" || c.Next != "t" {
+		t.Fatalf("claim c = %+v, %v, want page 1 from j to t reclaimed", c, err)
+	}
+
+	// an expired lease is reclaimed, and its run can't commit it anymore
+	now = now.Add(2 * time.Minute)
+	d, err := claimPage(ctx, r, "tip_rank", "d", "")
+	if err != nil || !d.Reclaimed || d.PageIndex != 0 {
+		t.Fatalf("claim d = %+v, %v, want page 0 reclaimed", d, err)
+	}
+	if err := r.CommitCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, "a"); !errors.Is(err, checkpoint.ErrCheckpointNotClaimed) {
+		t.Errorf("commit a = %v, want ErrCheckpointNotClaimed", err)
+	}
+
+	for _, runID := range []string{"c", "d"} {
+		if err := r.CommitCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, runID); err != nil {
+			t.Fatalf("commit %s: %v", runID, err)
+		}
+	}
+
+	if e, err := claimPage(ctx, r, "tip_rank", "e", ""); err != nil || e.Reclaimed || e.PageIndex != 2 || e.After != "t" {
+		t.Errorf("claim e = %+v, %v, want frontier page 2 after t", e, err)
+	}
+}
+
+func TestCheckpointMemoryKeepsSourcesApart(t *testing.T) {
+	ctx := context.Background()
+	r := NewCheckpointMemory(newTestLogger(t))
+
+	for _, runID := range []string{"a", "b"} {
+		if _, err := claimPage(ctx, r, "tip_rank", runID, "next"); err != nil {
+			t.Fatalf("claim tip_rank: %v", err)
+		}
+		if err := r.CommitCheckpoint(ctx, "TIP_RANK", consts.ASSET_PROFILE_JOB, runID); err != nil {
+			t.Fatalf("commit tip_rank: %v", err)
+		}
+	}
+
+	// another source starts from its own first page while the tip_rank frontier is locked
+	if _, err := r.ClaimCheckpoint(ctx, "tip_rank", consts.ASSET_PROFILE_JOB, "c", 10, time.Minute); err != nil {
+		t.Fatalf("claim tip_rank: %v", err)
+	}
+
+	cp, err := r.ClaimCheckpoint(ctx, "yahoo", consts.ASSET_PROFILE_JOB, "d", 10, time.Minute)
+	if err != nil {
+		t.Fatalf("claim yahoo: %v", err)
+	}
+	if cp.PageIndex != 0 || cp.Source != "YAHOO" {
+		t.Errorf("yahoo checkpoint = %+v, want page 0 of YAHOO", cp)
+	}
+}
+
+func TestAssetProfileMemoryKeepsFieldsMissingFromUpsert(t *testing.T) {
+	ctx := context.Background()
+	r := NewAssetProfileMemory(newTestLogger(t))
+
+	if _, err := r.UpsertAssetProfile(ctx, &entities.AssetProfile{Ticker: "RY", Sector: "Financial Services", Phone: "514-874-2110"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	if _, err := r.UpsertAssetProfile(ctx, &entities.AssetProfile{Ticker: "RY", Sector: "Banks"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	m, ok := r.FindAssetProfileModel("RY")
+	if !ok {
+		t.Fatal("asset profile not found")
+	}
+
+	if m.Sector != "Banks" || m.Phone != "514-874-2110" || m.CreatedAt == 0 {
+		t.Errorf("asset profile = %+v, want sector replaced, phone kept and createdAt set", m)
+	}
+}
+
+func TestAssetProfileMemoryRecordsChangesOnly(t *testing.T) {
+	ctx := context.Background()
+	r := NewAssetProfileMemory(newTestLogger(t))
+
+	profile := &entities.AssetProfile{Ticker: "RY", Sector: "Financial Services", Country: "Canada", FullTimeEmployees: 85301}
+	if unchanged, err := r.UpsertAssetProfile(ctx, profile); err != nil || unchanged {
+		t.Fatalf("first upsert = %v, %v, want changed", unchanged, err)
+	}
+
+	if unchanged, err := r.UpsertAssetProfile(ctx, profile); err != nil || !unchanged {
+		t.Fatalf("same upsert = %v, %v, want unchanged", unchanged, err)
+	}
+
+	m, _ := r.FindAssetProfileModel("RY")
+	if m.LastVerifiedAt == 0 {
+		t.Errorf("asset profile = %+v, want lastVerifiedAt set", m)
+	}
+
+	if changes := r.FindProfileChangeModels("RY"); len(changes) != 0 {
+		t.Errorf("changes = %d, want none before a reclassification", len(changes))
+	}
+
+	// missing fields keep their stored value and are not changes
+	if unchanged, err := r.UpsertAssetProfile(ctx, &entities.AssetProfile{Ticker: "RY", Sector: "Banks", FullTimeEmployees: 85301}); err != nil || unchanged {
+		t.Fatalf("reclassified upsert = %v, %v, want changed", unchanged, err)
+	}
+
+	changes := r.FindProfileChangeModels("RY")
+	if len(changes) != 1 {
+		t.Fatalf("changes = %d, want 1", len(changes))
+	}
+	if c := changes[0]; c.Field != "sector" || c.OldValue != "Financial Services" || c.NewValue != "Banks" || c.ObservedAt == 0 {
+		t.Errorf("change = %+v, want sector from Financial Services to Banks", c)
+	}
+}
+
+func TestAssetProfileMemoryRecordsNormalizedFieldChanges(t *testing.T) {
+	ctx := context.Background()
+	r := NewAssetProfileMemory(newTestLogger(t))
+
+	profile := &entities.AssetProfile{
+		Ticker:                "RY",
+		Country:               "Canada",
+		CountryRaw:            "Canada",
+		CountryCode:           "CA",
+		CountryAlpha3:         "CAN",
+		GicsSectorCode:        "40",
+		GicsSector:            "Financials",
+		GicsIndustryGroupCode: "4010",
+		GicsIndustryGroup:     "Banks",
+		GicsIndustryCode:      "401010",
+		GicsIndustry:          "Banks",
+	}
+	if _, err := r.UpsertAssetProfile(ctx, profile); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	// same codes, renamed labels and a differently spelled country
+	renamed := *profile
+	renamed.CountryRaw = "CANADA"
+	renamed.GicsSector = "Financial"
+	renamed.GicsIndustryGroup = "Banking"
+	renamed.GicsIndustry = "Banking"
+	if unchanged, err := r.UpsertAssetProfile(ctx, &renamed); err != nil || unchanged {
+		t.Fatalf("renamed upsert = %v, %v, want changed", unchanged, err)
+	}
+
+	var fields []string
+	for _, c := range r.FindProfileChangeModels("RY") {
+		fields = append(fields, c.Field)
+	}
+	sort.Strings(fields)
+
+	if want := []string{"countryRaw", "gicsIndustry", "gicsIndustryGroup", "gicsSector"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("changed fields = %v, want %v", fields, want)
+	}
+}
+
+func TestFailedTickerMemoryQuarantinesAfterRepeatedFailures(t *testing.T) {
+	ctx := context.Background()
+	r := NewFailedTickerMemory(newTestLogger(t))
+
+	for i := 0; i < 3; i++ {
+		ft, err := r.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "GONE", Source: "tip_rank", StatusCode: 404}, 3)
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		if want := i == 2; ft.Quarantined != want || ft.FailureCount != int64(i+1) {
+			t.Errorf("failure %d = %+v, want quarantined %v", i+1, ft, want)
+		}
+	}
+
+	r.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "FLAKY", Source: "TIP_RANK"}, 3)
+
+	failed, _ := r.FindFailedTickersBySource(ctx, "Tip_Rank", 10)
+	if len(failed) != 1 || failed[0].Ticker != "FLAKY" {
+		t.Errorf("failed tickers = %+v, want only FLAKY", failed)
+	}
+
+	r.DeleteFailedTicker(ctx, "FLAKY", "tip_rank")
+	if failed, _ := r.FindFailedTickersBySource(ctx, "TIP_RANK", 10); len(failed) != 0 {
+		t.Errorf("failed tickers = %+v, want none", failed)
+	}
+}
+
+func TestFailedTickerMemoryKeepsFailureOfTickersNotAttempted(t *testing.T) {
+	ctx := context.Background()
+	r := NewFailedTickerMemory(newTestLogger(t))
+
+	failed, err := r.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "FLAKY", Source: "TIP_RANK", Reason: "status 503", StatusCode: 503, Attempts: 3}, 3)
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	got, err := r.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "FLAKY", Source: "TIP_RANK", Reason: "not attempted before the deadline", NotAttempted: true}, 3)
+	if err != nil {
+		t.Fatalf("upsert not attempted: %v", err)
+	}
+
+	want := *failed
+	want.NotAttempted = true
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("failed ticker = %+v, want %+v", *got, want)
+	}
+
+	// a ticker only ever left out of runs is queued without a failure
+	got, _ = r.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "LATE", Source: "TIP_RANK", Reason: "not attempted before the deadline", NotAttempted: true}, 3)
+	if got.FailureCount != 0 || got.Reason != "not attempted before the deadline" || got.FirstFailedAt == 0 {
+		t.Errorf("failed ticker = %+v, want queued without a failure", *got)
+	}
+}
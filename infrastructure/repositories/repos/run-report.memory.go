@@ -0,0 +1,56 @@
+package repos
+
+import (
+	"context"
+	"sync"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+)
+
+// RunReportMemory struct is an in-memory run report repo
+type RunReportMemory struct {
+	mu      sync.Mutex
+	log     logger.ContextLog
+	reports []*models.RunReportModel
+}
+
+// NewRunReportMemory creates new run report memory repo
+func NewRunReportMemory(log logger.ContextLog) *RunReportMemory {
+	return &RunReportMemory{
+		log: log,
+	}
+}
+
+// Close is a no-op kept for parity with RunReportMongo
+func (r *RunReportMemory) Close() {
+	r.log.Info(context.Background(), "close run report memory repo")
+}
+
+// FindAllRunReportModels returns the saved run reports, oldest first
+func (r *RunReportMemory) FindAllRunReportModels() []*models.RunReportModel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*models.RunReportModel{}, r.reports...)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Implement interface
+///////////////////////////////////////////////////////////////////////////////
+
+// InsertRunReport saves the report of a scrape run
+func (r *RunReportMemory) InsertRunReport(ctx context.Context, report *entities.RunReport) error {
+	m, err := models.NewRunReportModel(ctx, r.log, report, "")
+	if err != nil {
+		r.log.Error(ctx, "create model failed", "error", err)
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reports = append(r.reports, m)
+	return nil
+}
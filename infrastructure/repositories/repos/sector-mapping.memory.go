@@ -0,0 +1,61 @@
+package repos
+
+import (
+	"context"
+	"sync"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+)
+
+// SectorMappingMemory struct is an in-memory sector mapping repo
+type SectorMappingMemory struct {
+	mu       sync.RWMutex
+	log      logger.ContextLog
+	mappings []*models.SectorMappingModel
+}
+
+// NewSectorMappingMemory creates new sector mapping memory repo
+func NewSectorMappingMemory(log logger.ContextLog) *SectorMappingMemory {
+	return &SectorMappingMemory{
+		log: log,
+	}
+}
+
+// Close is a no-op kept for parity with SectorMappingMongo
+func (r *SectorMappingMemory) Close() {
+	r.log.Info(context.Background(), "close sector mapping memory repo")
+}
+
+// AddSectorMapping maps a Yahoo sector, or a Yahoo industry when it is set, to a GICS code
+func (r *SectorMappingMemory) AddSectorMapping(yahooSector string, yahooIndustry string, code string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mappings = append(r.mappings, &models.SectorMappingModel{
+		Enabled:       true,
+		YahooSector:   yahooSector,
+		YahooIndustry: yahooIndustry,
+		Code:          code,
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Implement interface
+///////////////////////////////////////////////////////////////////////////////
+
+// FindSectorMappings finds the enabled sector mappings
+func (r *SectorMappingMemory) FindSectorMappings(ctx context.Context) ([]*entities.SectorMapping, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var mappings []*entities.SectorMapping
+	for _, m := range r.mappings {
+		if m.Enabled && !m.Deleted {
+			mappings = append(mappings, m.ToEntity())
+		}
+	}
+
+	return mappings, nil
+}
@@ -0,0 +1,96 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+)
+
+// ShardMemory struct is an in-memory shard repo
+type ShardMemory struct {
+	mu     sync.Mutex
+	log    logger.ContextLog
+	shards map[string]*models.ShardModel
+}
+
+// NewShardMemory creates new shard memory repo
+func NewShardMemory(log logger.ContextLog) *ShardMemory {
+	return &ShardMemory{
+		log:    log,
+		shards: make(map[string]*models.ShardModel),
+	}
+}
+
+// Close is a no-op kept for parity with ShardMongo
+func (r *ShardMemory) Close() {
+	r.log.Info(context.Background(), "close shard memory repo")
+}
+
+// FindAllShardModels returns the saved shards of a job, by index
+func (r *ShardMemory) FindAllShardModels(jobID string) []*models.ShardModel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var shards []*models.ShardModel
+	for _, m := range r.shards {
+		if m.JobID == jobID {
+			shards = append(shards, m)
+		}
+	}
+
+	sort.Slice(shards, func(i, j int) bool { return shards[i].Index < shards[j].Index })
+	return shards
+}
+
+// shardKey returns the key of the shard of a job at an index
+func shardKey(jobID string, index int64) string {
+	return fmt.Sprintf("%s/%d", jobID, index)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Implement interface
+///////////////////////////////////////////////////////////////////////////////
+
+// InsertShards saves the shards of a job as they are handed out to the workers
+func (r *ShardMemory) InsertShards(ctx context.Context, shards []*entities.Shard) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, shard := range shards {
+		m, err := models.NewShardModel(ctx, r.log, shard, "")
+		if err != nil {
+			r.log.Error(ctx, "create model failed", "error", err)
+			return err
+		}
+
+		r.shards[shardKey(m.JobID, m.Index)] = m
+	}
+
+	return nil
+}
+
+// CompleteShard records the outcome of a shard reported back by its worker
+func (r *ShardMemory) CompleteShard(ctx context.Context, shard *entities.Shard) error {
+	m, err := models.NewShardModel(ctx, r.log, shard, "")
+	if err != nil {
+		r.log.Error(ctx, "create model failed", "error", err)
+		return err
+	}
+	m.CompletedAt = m.ModifiedAt
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the shard keeps the time it was handed out
+	if existing, ok := r.shards[shardKey(m.JobID, m.Index)]; ok {
+		m.CreatedAt = existing.CreatedAt
+	}
+
+	r.shards[shardKey(m.JobID, m.Index)] = m
+	return nil
+}
@@ -0,0 +1,189 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/config"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ShardMongo struct
+type ShardMongo struct {
+	db     *mongo.Database
+	client *mongo.Client
+	log    logger.ContextLog
+	conf   *config.MongoConfig
+}
+
+// NewShardMongo creates new shard mongo repo
+func NewShardMongo(db *mongo.Database, log logger.ContextLog, conf *config.MongoConfig) (*ShardMongo, error) {
+	if db != nil {
+		return &ShardMongo{
+			db:   db,
+			log:  log,
+			conf: conf,
+		}, nil
+	}
+
+	// set context with timeout from the config
+	// create new context for the query
+	ctx, cancel := createContext(context.Background(), conf.TimeoutMS)
+	defer cancel()
+
+	// set mongo client options
+	clientOptions := options.Client()
+
+	// set min pool size
+	if conf.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(conf.MinPoolSize)
+	}
+
+	// set max pool size
+	if conf.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(conf.MaxPoolSize)
+	}
+
+	// set max idle time ms
+	if conf.MaxIdleTimeMS > 0 {
+		clientOptions.SetMaxConnIdleTime(time.Duration(conf.MaxIdleTimeMS) * time.Millisecond)
+	}
+
+	// construct a connection string from mongo config object
+	cxnString := fmt.Sprintf("mongodb+srv://%s:%s@%s", conf.Username, conf.Password, conf.Host)
+
+	// create mongo client by making new connection
+	client, err := mongo.Connect(ctx, clientOptions.ApplyURI(cxnString))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ShardMongo{
+		db:     client.Database(conf.Dbname),
+		client: client,
+		log:    log,
+		conf:   conf,
+	}, nil
+}
+
+// Close disconnect from database
+func (r *ShardMongo) Close() {
+	ctx := context.Background()
+	r.log.Info(ctx, "close mongo client")
+
+	if r.client == nil {
+		return
+	}
+
+	if err := r.client.Disconnect(ctx); err != nil {
+		r.log.Error(ctx, "disconnect mongo failed", "error", err)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Implement interface
+///////////////////////////////////////////////////////////////////////////////
+
+// InsertShards saves the shards of a job as they are handed out to the workers
+func (r *ShardMongo) InsertShards(ctx context.Context, shards []*entities.Shard) error {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	var docs []interface{}
+	for _, shard := range shards {
+		m, err := models.NewShardModel(ctx, r.log, shard, r.conf.SchemaVersion)
+		if err != nil {
+			r.log.Error(ctx, "create model failed", "error", err)
+			return err
+		}
+		docs = append(docs, m)
+	}
+
+	if len(docs) == 0 {
+		return nil
+	}
+
+	// what collection we are going to use
+	colname, ok := r.conf.Colnames[consts.SCRAPE_SHARDS_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return fmt.Errorf("cannot find collection name")
+	}
+	col := r.db.Collection(colname)
+
+	if _, err := col.InsertMany(ctx, docs); err != nil {
+		r.log.Error(ctx, "insert many failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// CompleteShard records the outcome of a shard reported back by its worker
+func (r *ShardMongo) CompleteShard(ctx context.Context, shard *entities.Shard) error {
+	// create new context for the query
+	ctx, cancel := createContext(ctx, r.conf.TimeoutMS)
+	defer cancel()
+
+	m, err := models.NewShardModel(ctx, r.log, shard, r.conf.SchemaVersion)
+	if err != nil {
+		r.log.Error(ctx, "create model failed", "error", err)
+		return err
+	}
+	m.CompletedAt = m.ModifiedAt
+
+	// what collection we are going to use
+	colname, ok := r.conf.Colnames[consts.SCRAPE_SHARDS_COLLECTION]
+	if !ok {
+		r.log.Error(ctx, "cannot find collection name")
+		return fmt.Errorf("cannot find collection name")
+	}
+	col := r.db.Collection(colname)
+
+	filter := bson.D{
+		{
+			Key:   "jobId",
+			Value: m.JobID,
+		},
+		{
+			Key:   "index",
+			Value: m.Index,
+		},
+	}
+
+	// the shard keeps the time it was handed out
+	m.CreatedAt = 0
+
+	update := bson.D{
+		{
+			Key:   "$set",
+			Value: m,
+		},
+		{
+			Key: "$setOnInsert",
+			Value: bson.D{
+				{
+					Key:   "createdAt",
+					Value: time.Now().UTC().Unix(),
+				},
+			},
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+
+	if _, err := col.UpdateOne(ctx, filter, update, opts); err != nil {
+		r.log.Error(ctx, "update one failed", "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package repos
+
+import (
+	"context"
+	"sync"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/models"
+)
+
+// SymbolOverrideMemory struct is an in-memory symbol override repo
+type SymbolOverrideMemory struct {
+	mu        sync.RWMutex
+	log       logger.ContextLog
+	overrides map[string]*models.SymbolOverrideModel
+}
+
+// NewSymbolOverrideMemory creates new symbol override memory repo
+func NewSymbolOverrideMemory(log logger.ContextLog) *SymbolOverrideMemory {
+	return &SymbolOverrideMemory{
+		log:       log,
+		overrides: make(map[string]*models.SymbolOverrideModel),
+	}
+}
+
+// Close is a no-op kept for parity with SymbolOverrideMongo
+func (r *SymbolOverrideMemory) Close() {
+	r.log.Info(context.Background(), "close symbol override memory repo")
+}
+
+// AddSymbolOverride maps a ticker to a Yahoo symbol
+func (r *SymbolOverrideMemory) AddSymbolOverride(ticker string, yahooSymbol string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.overrides[ticker] = &models.SymbolOverrideModel{
+		Enabled:     true,
+		Ticker:      ticker,
+		YahooSymbol: yahooSymbol,
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Implement interface
+///////////////////////////////////////////////////////////////////////////////
+
+// FindSymbolOverrides finds the enabled Yahoo symbol overrides of tickers
+func (r *SymbolOverrideMemory) FindSymbolOverrides(ctx context.Context, tickers []string) ([]*entities.SymbolOverride, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var overrides []*entities.SymbolOverride
+	for _, ticker := range tickers {
+		if m, ok := r.overrides[ticker]; ok && m.Enabled && !m.Deleted {
+			overrides = append(overrides, m.ToEntity())
+		}
+	}
+
+	return overrides, nil
+}
@@ -2,10 +2,13 @@ package scraper
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly"
 	"github.com/gocolly/colly/extensions"
 	"github.com/google/uuid"
@@ -14,7 +17,12 @@ import (
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/config"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/assets"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/failure"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/profile"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/report"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/sector"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/shard"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/symbol"
 )
 
 // AssetProfileScraper struct
@@ -23,38 +31,231 @@ type AssetProfileScraper struct {
 	assetProfileService   *profile.Service
 	assetService          *assets.Service
 	log                   logger.ContextLog
+	extractor             ProfileExtractor
+	replayDir             string
+	recordDir             string
+	offline               bool
+	fixtures              bool
+	retryPolicy           RetryPolicy
+	failedTickerService   *failure.Service
+	drainSize             int64
+	runReportService      *report.Service
+	deadlineMargin        time.Duration
+	randomDelay           time.Duration
+	refreshPolicy         *entities.RefreshPolicy
+	shardService          *shard.Service
+	symbolService         *symbol.Service
+	sectorService         *sector.Service
+	classifier            *sector.Classifier
+	yahooSites            *entities.YahooSites
+	runMu                 sync.Mutex
+	run                   *runRecorder
+	results               *resultCollector
+	slots                 chan struct{}
+	stop                  context.Context
+	mu                    sync.Mutex
+	attempts              map[string]int
 	errorTickers          []string
 	scrapedTickers        []string
 }
 
+// ScraperOption configures an asset profile scraper
+type ScraperOption func(*AssetProfileScraper)
+
+// WithProfileExtractor sets the extractor used to parse the profile pages
+func WithProfileExtractor(extractor ProfileExtractor) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.extractor = extractor
+	}
+}
+
+// WithReplayDir replays the profile pages saved in dir instead of fetching them from Yahoo.
+// The replay takes precedence over WithRecordDir, replayed pages are never recorded
+func WithReplayDir(dir string) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.replayDir = dir
+	}
+}
+
+// WithRecordDir saves every fetched profile page to dir so it can be replayed later
+func WithRecordDir(dir string) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.recordDir = dir
+	}
+}
+
+// WithRetryPolicy sets how failed profile requests are retried
+func WithRetryPolicy(policy RetryPolicy) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.retryPolicy = policy
+	}
+}
+
+// WithFailedTickerService persists the failed tickers so they can be scraped again later
+func WithFailedTickerService(failedTickerService *failure.Service) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.failedTickerService = failedTickerService
+	}
+}
+
+// WithFailedTickerDrain retries up to size failed tickers before each checkpoint page
+func WithFailedTickerDrain(size int64) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.drainSize = size
+	}
+}
+
+// WithRunReportService saves the report of every scrape run
+func WithRunReportService(runReportService *report.Service) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.runReportService = runReportService
+	}
+}
+
+// WithDeadlineMargin sets how long before the context deadline a run stops requesting tickers
+func WithDeadlineMargin(margin time.Duration) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.deadlineMargin = margin
+	}
+}
+
+// WithRandomDelay sets the maximum random delay added between the requests to a Yahoo site
+func WithRandomDelay(delay time.Duration) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.randomDelay = delay
+	}
+}
+
+// WithRefreshPolicy sets how old a profile may get before the stale scrapes pick its asset
+func WithRefreshPolicy(policy *entities.RefreshPolicy) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.refreshPolicy = policy
+	}
+}
+
+// WithShardService reports the outcome of every shard scraped back to the shard tracking
+func WithShardService(shardService *shard.Service) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.shardService = shardService
+	}
+}
+
+// WithSymbolService requests the profiles by the Yahoo symbols of the tickers
+// instead of the tickers themselves
+func WithSymbolService(symbolService *symbol.Service) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.symbolService = symbolService
+	}
+}
+
+// WithSectorService classifies the profiles into the GICS-like taxonomy of the sector mappings
+func WithSectorService(sectorService *sector.Service) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.sectorService = sectorService
+	}
+}
+
+// WithYahooSites requests the profile pages from the regional Yahoo sites, falling back
+// to the next region of a ticker when a site does not have its profile
+func WithYahooSites(sites *entities.YahooSites) ScraperOption {
+	return func(s *AssetProfileScraper) {
+		s.yahooSites = sites
+	}
+}
+
 // NewAssetProfileScraper create new asset profile scraper
-func NewAssetProfileScraper(assetService *assets.Service, assetProfileService *profile.Service, log logger.ContextLog) *AssetProfileScraper {
-	scrapeAssetProfileJob := newScraperJob()
+func NewAssetProfileScraper(assetService *assets.Service, assetProfileService *profile.Service, log logger.ContextLog, opts ...ScraperOption) *AssetProfileScraper {
+	s := &AssetProfileScraper{
+		assetProfileService: assetProfileService,
+		assetService:        assetService,
+		log:                 log,
+		extractor:           DefaultProfileExtractor(),
+		retryPolicy:         DefaultRetryPolicy(),
+		deadlineMargin:      DefaultDeadlineMargin,
+		randomDelay:         DefaultRandomDelay,
+		refreshPolicy:       &entities.RefreshPolicy{},
+		yahooSites:          config.DefaultYahooSites(),
+		attempts:            make(map[string]int),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.replayDir != "" && s.recordDir != "" {
+		s.log.Error(context.Background(), "record dir ignored while replaying", "replayDir", s.replayDir, "recordDir", s.recordDir)
+		s.recordDir = ""
+	}
+	s.offline = s.replayDir != ""
+	s.fixtures = s.replayDir != "" || s.recordDir != ""
+
+	// replayed pages are not rate limited
+	if s.offline {
+		s.randomDelay = 0
+	}
+
+	s.ScrapeAssetProfileJob = newScraperJob(s.latencyTransport(), s.randomDelay, allowedDomains(s.yahooSites))
+	s.configJobs()
+
+	return s
+}
 
-	return &AssetProfileScraper{
-		ScrapeAssetProfileJob: scrapeAssetProfileJob,
-		assetProfileService:   assetProfileService,
-		assetService:          assetService,
-		log:                   log,
+// fixtureTransport returns the transport replaying or recording the profile pages,
+// or the http.DefaultTransport when neither is configured
+func (s *AssetProfileScraper) fixtureTransport() http.RoundTripper {
+	switch {
+	case s.replayDir != "":
+		return NewReplayTransport(s.replayDir)
+	case s.recordDir != "":
+		return NewRecordTransport(s.recordDir, nil)
+	default:
+		return http.DefaultTransport
 	}
 }
 
-// newScraperJob creates a new colly collector with some custom configs
-func newScraperJob() *colly.Collector {
+// latencyTransport times the requests going through the fixture transport
+func (s *AssetProfileScraper) latencyTransport() http.RoundTripper {
+	return &latencyTransport{
+		next: s.fixtureTransport(),
+		observe: func(latency time.Duration) {
+			if s.run != nil {
+				s.run.observeLatency(latency)
+			}
+		},
+	}
+}
+
+// parallelism is the number of tickers scraped at the same time
+const parallelism = 2
+
+// DefaultRandomDelay is the maximum random delay added between the requests to a Yahoo site
+const DefaultRandomDelay = 2 * time.Second
+
+// DefaultDeadlineMargin leaves enough time for the last requests, which may
+// take the whole request timeout plus the random delay, to complete
+const DefaultDeadlineMargin = 40 * time.Second
+
+// newScraperJob creates a new colly collector with some custom configs. Requests go through
+// the transport to the allowed domains, each after a random delay up to randomDelay
+func newScraperJob(transport http.RoundTripper, randomDelay time.Duration, domains []string) *colly.Collector {
 	c := colly.NewCollector(
-		colly.AllowedDomains(config.AllowDomain),
+		colly.AllowedDomains(domains...),
 		colly.Async(true),
+		// runs dedupe their own tickers, and a reused scraper requests them again
+		colly.AllowURLRevisit(),
 	)
 
 	// Overrides the default timeout (10 seconds) for this collector
 	c.SetRequestTimeout(30 * time.Second)
 
+	c.WithTransport(transport)
+
 	// Limit the number of threads started by colly to two
 	// when visiting links which domains' matches "*httpbin.*" glob
 	c.Limit(&colly.LimitRule{
 		DomainGlob:  config.DomainGlob,
-		Parallelism: 2,
-		RandomDelay: 2 * time.Second,
+		Parallelism: parallelism,
+		RandomDelay: randomDelay,
 	})
 
 	extensions.RandomUserAgent(c)
@@ -63,180 +264,681 @@ func newScraperJob() *colly.Collector {
 	return c
 }
 
-// configJobs configs on error handler and on response handler for scaper jobs
+// configJobs configs on error handler and on response handler for scaper jobs.
+// It is called once by the constructor so reused scrapers don't stack handlers
 func (s *AssetProfileScraper) configJobs() {
 	s.ScrapeAssetProfileJob.OnError(s.errorHandler)
+	s.ScrapeAssetProfileJob.OnResponse(s.processAssetProfileResponse)
 	s.ScrapeAssetProfileJob.OnScraped(s.scrapedHandler)
-	s.ScrapeAssetProfileJob.OnHTML("div[data-test=qsp-profile]", s.processAssetProfileResponse)
 }
 
-// ScrapeAssetProfilesByTickers scrape asset profiles by tickers
-func (s *AssetProfileScraper) ScrapeAssetProfilesByTickers(tickers []string) {
-	ctx := context.Background()
+// ScrapeAssetProfilesByTickers scrape asset profiles by tickers. The tickers have no source,
+// so their failures are not recorded for the failed ticker drain
+func (s *AssetProfileScraper) ScrapeAssetProfilesByTickers(ctx context.Context, tickers []string) *entities.RunReport {
+	return s.ScrapeAssetProfilesBySourceTickers(ctx, "", tickers)
+}
 
-	s.configJobs()
+// ScrapeAssetProfilesBySourceTickers scrape asset profiles by tickers of a source. Their failures
+// are recorded for the failed ticker drain of the source, unless the source is empty
+func (s *AssetProfileScraper) ScrapeAssetProfilesBySourceTickers(ctx context.Context, source string, tickers []string) *entities.RunReport {
+	return s.scrapeRun(ctx, source, func(ctx context.Context) {
+		s.scrapeTickers(ctx, source, tickers)
+	})
+}
 
-	for _, ticker := range tickers {
-		reqContext := colly.NewContext()
-		reqContext.Put("ticker", ticker)
+// ScrapeAllAssetProfilesBySource scrape asset profiles by sources
+func (s *AssetProfileScraper) ScrapeAllAssetProfilesBySource(ctx context.Context, source string) *entities.RunReport {
+	return s.scrapeRun(ctx, source, func(ctx context.Context) {
+		assets, err := s.assetService.GetAssetsBySource(ctx, source)
+		if err != nil {
+			s.log.Error(ctx, "scraping asset profile failed", "error", err)
+			return
+		}
 
-		url := config.GetAssetProfileByTickerURL(ticker)
+		s.scrapeTickers(ctx, source, tickersOf(assets))
+	})
+}
 
-		s.log.Info(ctx, "scraping asset profile", "ticker", ticker)
-		if err := s.ScrapeAssetProfileJob.Request("GET", url, nil, reqContext, nil); err != nil {
-			s.log.Error(ctx, "scraping asset profile", "error", err, "ticker", ticker)
+// ScrapeAssetProfilesBySourceFromCheckpoint scrape asset profiles by source from checkpoint.
+// The checkpoint page is claimed for the run and only committed once it is complete.
+// When a failed ticker drain is configured, failed tickers are retried before the page
+func (s *AssetProfileScraper) ScrapeAssetProfilesBySourceFromCheckpoint(ctx context.Context, source string, pageSize int64) *entities.RunReport {
+	return s.scrapeRun(ctx, source, func(ctx context.Context) {
+		var tickers []string
+		if s.drainSize > 0 {
+			tickers = s.getFailedTickers(ctx, source, s.drainSize)
 		}
+
+		runID := s.run.runID()
+
+		assets, checkpoint, err := s.assetService.GetAssetsBySourceFromCheckpoint(ctx, runID, source, pageSize)
+		if err != nil {
+			s.log.Error(ctx, "scraping asset profile failed", "error", err)
+			return
+		}
+		s.run.checkpoint(checkpoint)
+
+		s.scrapeTickers(ctx, source, append(tickers, tickersOf(assets)...))
+		s.releaseCheckpoint(ctx, checkpoint)
+	})
+}
+
+// releaseCheckpoint commits the checkpoint page once all its tickers were attempted,
+// and abandons it otherwise so the next run scrapes the page again
+func (s *AssetProfileScraper) releaseCheckpoint(ctx context.Context, checkpoint *entities.Checkpoint) {
+	if s.run.notAttemptedCount() > 0 {
+		s.log.Info(ctx, "page is not complete, abandon checkpoint", "runId", checkpoint.RunID)
+		s.assetService.AbandonCheckpoint(ctx, checkpoint.Source, checkpoint.RunID)
+		return
 	}
 
-	s.ScrapeAssetProfileJob.Wait()
+	if err := s.assetService.CommitCheckpoint(ctx, checkpoint); err != nil {
+		s.log.Error(ctx, "commit checkpoint failed", "error", err, "runId", checkpoint.RunID)
+	}
 }
 
-// ScrapeAllAssetProfilesBySource scrape asset profiles by sources
-func (s *AssetProfileScraper) ScrapeAllAssetProfilesBySource(source string) {
-	ctx := context.Background()
+// ScrapeStaleAssetProfilesBySource scrapes up to limit assets of a source by how due they are:
+// never scraped first, then failed, then the oldest profiles past the max age of the refresh policy
+func (s *AssetProfileScraper) ScrapeStaleAssetProfilesBySource(ctx context.Context, source string, limit int64) *entities.RunReport {
+	return s.scrapeRun(ctx, source, func(ctx context.Context) {
+		assets, err := s.assetService.GetStaleAssetsBySource(ctx, source, s.refreshPolicy, limit)
+		if err != nil {
+			s.log.Error(ctx, "scraping asset profile failed", "error", err)
+			return
+		}
 
-	s.configJobs()
+		s.scrapeTickers(ctx, source, tickersOf(assets))
+	})
+}
 
-	assets, err := s.assetService.GetAssetsBySource(ctx, source)
-	if err != nil {
-		s.log.Error(ctx, "scraping asset profile failed", "error", err)
-		return
+// ScrapeAssetProfilesByShard scrapes the assets of a shard handed out by a coordinator and,
+// when a shard service is configured, reports the outcome of the shard back
+func (s *AssetProfileScraper) ScrapeAssetProfilesByShard(ctx context.Context, shard *entities.Shard) *entities.RunReport {
+	loaded := false
+
+	runReport := s.scrapeRun(ctx, shard.Source, func(ctx context.Context) {
+		assets, err := s.assetService.GetAssetsBySourceFromShard(ctx, shard)
+		if err != nil {
+			s.log.Error(ctx, "scraping asset profile failed", "error", err)
+			return
+		}
+		loaded = true
+
+		s.scrapeTickers(ctx, shard.Source, tickersOf(assets))
+	})
+
+	// a shard whose assets could not be loaded stays pending
+	if loaded && s.shardService != nil {
+		if err := s.shardService.CompleteShard(ctx, shard, runReport); err != nil {
+			s.log.Error(ctx, "complete shard failed", "error", err, "jobId", shard.JobID, "index", shard.Index)
+		}
 	}
 
-	for _, asset := range assets {
-		reqContext := colly.NewContext()
-		reqContext.Put("ticker", asset.Ticker)
+	return runReport
+}
 
-		url := config.GetAssetProfileByTickerURL(asset.Ticker)
+// ScrapeFailedAssetProfiles drains the failed tickers of a source, oldest failure first
+func (s *AssetProfileScraper) ScrapeFailedAssetProfiles(ctx context.Context, source string, limit int64) *entities.RunReport {
+	return s.scrapeRun(ctx, source, func(ctx context.Context) {
+		s.scrapeTickers(ctx, source, s.getFailedTickers(ctx, source, limit))
+	})
+}
 
-		s.log.Info(ctx, "scraping asset profile", "ticker", asset.Ticker)
-		if err := s.ScrapeAssetProfileJob.Request("GET", url, nil, reqContext, nil); err != nil {
-			s.log.Error(ctx, "scraping asset profile", "error", err, "ticker", asset.Ticker)
+// scrapeRun runs scrape as a new scrape run and returns its report, saving it
+// when a run report service is configured. Runs of a scraper never overlap, and
+// stop requesting tickers once the context deadline is closer than the margin
+func (s *AssetProfileScraper) scrapeRun(ctx context.Context, source string, scrape func(ctx context.Context)) *entities.RunReport {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	stop, cancel := s.stopContext(ctx)
+	defer cancel()
+
+	s.run = newRunRecorder(source)
+	s.results = newResultCollector(s.handleResult)
+	s.slots = make(chan struct{}, parallelism)
+	s.stop = stop
+
+	// the mappings are read once per run, so edits to them apply from the next run
+	s.classifier = nil
+	if s.sectorService != nil {
+		s.classifier = s.sectorService.NewClassifier(ctx)
+	}
+
+	scrape(ctx)
+
+	s.results.close()
+	runReport := s.run.finish()
+
+	s.log.Info(ctx, "scrape run finished", "runId", runReport.RunID, "requested", runReport.Requested, "succeeded", runReport.Succeeded, "failed", runReport.Failed, "skipped", runReport.Skipped)
+
+	if s.runReportService != nil {
+		if err := s.runReportService.AddRunReport(ctx, runReport); err != nil {
+			s.log.Error(ctx, "add run report failed", "error", err, "runId", runReport.RunID)
 		}
 	}
 
-	s.ScrapeAssetProfileJob.Wait()
+	return runReport
 }
 
-// ScrapeAssetProfilesBySourceFromCheckpoint scrape asset profiles by source from checkpoint
-func (s *AssetProfileScraper) ScrapeAssetProfilesBySourceFromCheckpoint(source string, pageSize int64) {
-	ctx := context.Background()
+// stopContext returns a context done once the deadline of ctx is closer than the margin
+func (s *AssetProfileScraper) stopContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
 
-	s.configJobs()
+	return context.WithDeadline(ctx, deadline.Add(-s.deadlineMargin))
+}
+
+// getFailedTickers gets the failed tickers of a source that are not quarantined
+func (s *AssetProfileScraper) getFailedTickers(ctx context.Context, source string, limit int64) []string {
+	if s.failedTickerService == nil {
+		return nil
+	}
 
-	assets, err := s.assetService.GetAssetsBySourceFromCheckpoint(ctx, source, pageSize)
+	failedTickers, err := s.failedTickerService.GetFailedTickers(ctx, source, limit)
 	if err != nil {
-		s.log.Error(ctx, "scraping asset profile failed", "error", err)
-		return
+		s.log.Error(ctx, "get failed tickers failed", "error", err, "source", source)
+		return nil
 	}
 
-	for _, asset := range assets {
-		reqContext := colly.NewContext()
-		reqContext.Put("ticker", asset.Ticker)
+	var tickers []string
+	for _, failedTicker := range failedTickers {
+		tickers = append(tickers, failedTicker.Ticker)
+	}
+
+	return tickers
+}
 
-		url := config.GetAssetProfileByTickerURL(asset.Ticker)
+// scrapeTickers requests the profile page of each ticker, a few at a time so it can stop
+// before the deadline, and waits for all of them. The tickers left out are recorded as
+// not attempted and queued with the failed tickers so the next run picks them up
+func (s *AssetProfileScraper) scrapeTickers(ctx context.Context, source string, tickers []string) {
+	tickers = uniqueTickers(tickers)
+	s.run.requested(len(tickers))
 
-		s.log.Info(ctx, "scraping asset profile", "ticker", asset.Ticker)
-		if err := s.ScrapeAssetProfileJob.Request("GET", url, nil, reqContext, nil); err != nil {
-			s.log.Error(ctx, "scraping asset profile failed", "error", err, "ticker", asset.Ticker)
+	symbols := s.yahooSymbols(ctx, tickers)
+
+	for i, ticker := range tickers {
+		select {
+		case s.slots <- struct{}{}:
+		case <-s.stop.Done():
+		}
+
+		// a free slot and the stop may come at the same time
+		if s.stop.Err() != nil {
+			s.log.Info(ctx, "deadline is near, stop requesting tickers", "notAttempted", len(tickers)-i)
+			s.notAttempted(ctx, source, tickers[i:])
+			break
+		}
+
+		reqContext := colly.NewContext()
+		reqContext.Put("ticker", ticker)
+		reqContext.Put("symbol", symbols[ticker])
+		reqContext.Put("source", source)
+		reqContext.Put("regions", regionsOf(s.yahooSites, source, ticker))
+
+		s.log.Info(ctx, "scraping asset profile", "ticker", ticker, "symbol", symbols[ticker])
+		if err := s.requestRegion(reqContext, 0); err != nil {
+			s.log.Error(ctx, "scraping asset profile failed", "error", err, "ticker", ticker)
+			s.run.skipped()
+			<-s.slots
 		}
 	}
 
 	s.ScrapeAssetProfileJob.Wait()
 }
 
+// requestRegion requests the profile page of the ticker of the request context from
+// the site of the region at index in the regions of the ticker
+func (s *AssetProfileScraper) requestRegion(reqCtx *colly.Context, index int) error {
+	regions, _ := reqCtx.GetAny("regions").([]string)
+	if index >= len(regions) {
+		return fmt.Errorf("no yahoo site for ticker %s", reqCtx.Get("ticker"))
+	}
+
+	reqCtx.Put("region", regions[index])
+	reqCtx.Put("regionIndex", index)
+
+	u := profileURL(s.yahooSites, regions[index], reqCtx.Get("symbol"))
+
+	// saved pages are keyed by ticker and region, not by the symbol in the url
+	var hdr http.Header
+	if s.fixtures {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return err
+		}
+		hdr = fixtureHeader(reqCtx.Get("ticker"), regions[index], parsed.Path)
+	}
+
+	return s.ScrapeAssetProfileJob.Request("GET", u, nil, reqCtx, hdr)
+}
+
+// fallBack outcomes
+const (
+	// fellBack requested the profile page from the next region
+	fellBack = iota
+	// regionsExhausted means every region of the ticker was tried
+	regionsExhausted
+	// regionsLeft means some regions were left untried, the run is stopping or the request failed
+	regionsLeft
+)
+
+// fallBack requests the profile page from the next region of the ticker when the site of the
+// current region does not have it, and returns the outcome. The ticker keeps its slot
+func (s *AssetProfileScraper) fallBack(r *colly.Response) int {
+	ctx := context.Background()
+	reqCtx := r.Request.Ctx
+
+	index, _ := reqCtx.GetAny("regionIndex").(int)
+	regions, _ := reqCtx.GetAny("regions").([]string)
+	if index+1 >= len(regions) {
+		return regionsExhausted
+	}
+
+	if s.stop.Err() != nil {
+		s.log.Info(ctx, "deadline is near, not falling back", "ticker", reqCtx.Get("ticker"), "region", regions[index])
+		return regionsLeft
+	}
+
+	s.log.Info(ctx, "profile not found, falling back", "ticker", reqCtx.Get("ticker"), "region", regions[index], "next", regions[index+1])
+
+	// every site gets its own retries
+	reqCtx.Put("attempt", 1)
+
+	if err := s.requestRegion(reqCtx, index+1); err != nil {
+		s.log.Error(ctx, "fall back failed", "error", err, "ticker", reqCtx.Get("ticker"))
+		return regionsLeft
+	}
+
+	return fellBack
+}
+
+// profileNotFound falls back to the next region of the ticker whose profile the site does not have.
+// The ticker is not found once every region was tried; with regions left untried it only fails,
+// so it is retried later and does not count towards soft deleting its profile
+func (s *AssetProfileScraper) profileNotFound(r *colly.Response) {
+	switch s.fallBack(r) {
+	case regionsExhausted:
+		s.notFoundTicker(r.Request.Ctx, r.StatusCode)
+	case regionsLeft:
+		s.failTicker(r.Request.Ctx, "profile not found on the yahoo site of region "+r.Request.Ctx.Get("region")+", the next regions were not tried", r.StatusCode)
+	}
+}
+
+// yahooSymbols maps the tickers to their Yahoo symbols when a symbol service is configured,
+// and to themselves otherwise
+func (s *AssetProfileScraper) yahooSymbols(ctx context.Context, tickers []string) map[string]string {
+	if s.symbolService != nil {
+		return s.symbolService.ResolveYahooSymbols(ctx, tickers)
+	}
+
+	symbols := make(map[string]string, len(tickers))
+	for _, ticker := range tickers {
+		symbols[ticker] = ticker
+	}
+
+	return symbols
+}
+
+// notAttempted records the tickers left out of the run, queuing them with the failed
+// tickers without counting a failure when a failed ticker service is configured
+func (s *AssetProfileScraper) notAttempted(ctx context.Context, source string, tickers []string) {
+	s.run.notAttempted(tickers)
+
+	// failed tickers are drained by source, the ones scraped without a source would never be
+	if s.failedTickerService == nil || source == "" {
+		return
+	}
+
+	for _, ticker := range tickers {
+		failedTicker := &entities.FailedTicker{
+			Ticker:       ticker,
+			Source:       source,
+			Reason:       "not attempted before the deadline",
+			NotAttempted: true,
+		}
+
+		if err := s.failedTickerService.RecordFailure(ctx, failedTicker); err != nil {
+			s.log.Error(ctx, "record not attempted ticker failed", "error", err, "ticker", ticker)
+		}
+	}
+}
+
 ///////////////////////////////////////////////////////////
 // Scraper Handler
 ///////////////////////////////////////////////////////////
 
-// errorHandler generic error handler for all scaper jobs
+// errorHandler generic error handler for all scaper jobs. Retryable failures are
+// re-enqueued on the same collector after a backoff, keeping the request context
 func (s *AssetProfileScraper) errorHandler(r *colly.Response, err error) {
 	ctx := context.Background()
-	s.log.Error(ctx, "failed to request url", "url", r.Request.URL, "error", err)
-	s.errorTickers = append(s.errorTickers, r.Request.Ctx.Get("ticker"))
+	ticker := r.Request.Ctx.Get("ticker")
+	attempt := requestAttempt(r.Request.Ctx)
+
+	if s.retryPolicy.ShouldRetry(attempt, r.StatusCode, err) {
+		delay := s.retryPolicy.Backoff(attempt, r)
+
+		if !s.canRetry(delay) {
+			s.log.Info(ctx, "deadline is near, not retrying", "url", r.Request.URL, "attempt", attempt, "delay", delay.String())
+			s.failTicker(r.Request.Ctx, err.Error(), r.StatusCode)
+			return
+		}
+
+		s.log.Info(ctx, "retrying request", "url", r.Request.URL, "error", err, "status", r.StatusCode, "attempt", attempt, "delay", delay.String())
+
+		// the run may stop during the backoff, then the ticker fails instead of holding its slot
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-s.stop.Done():
+			timer.Stop()
+			s.log.Info(ctx, "deadline is near, retry cancelled", "url", r.Request.URL, "attempt", attempt)
+			s.failTicker(r.Request.Ctx, err.Error(), r.StatusCode)
+			return
+		}
+
+		r.Request.Ctx.Put("attempt", attempt+1)
+		s.recordAttempts(ticker, attempt+1)
+
+		retryErr := r.Request.Retry()
+		if retryErr == nil {
+			return
+		}
+
+		s.log.Error(ctx, "retry request failed", "url", r.Request.URL, "error", retryErr, "ticker", ticker)
+	}
+
+	s.log.Error(ctx, "failed to request url", "url", r.Request.URL, "error", err, "status", r.StatusCode, "attempts", attempt)
+
+	if r.StatusCode == http.StatusNotFound {
+		s.profileNotFound(r)
+		return
+	}
+
+	s.failTicker(r.Request.Ctx, err.Error(), r.StatusCode)
+}
+
+// canRetry reports whether a retry after delay starts before the run stops
+func (s *AssetProfileScraper) canRetry(delay time.Duration) bool {
+	if s.stop.Err() != nil {
+		return false
+	}
+
+	stopAt, ok := s.stop.Deadline()
+	return !ok || time.Now().Add(delay).Before(stopAt)
+}
+
+// recordAttempts records the number of attempts made for a retried ticker
+func (s *AssetProfileScraper) recordAttempts(ticker string, attempts int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts[ticker] = attempts
 }
 
 func (s *AssetProfileScraper) scrapedHandler(r *colly.Response) {
 	ctx := context.Background()
-	foundSector := r.Ctx.Get("foundSector")
-	if foundSector == "" {
-		s.log.Error(ctx, "sector not found", "ticker", r.Request.Ctx.Get("ticker"))
-		s.errorTickers = append(s.errorTickers, r.Request.Ctx.Get("ticker"))
+	ticker := r.Request.Ctx.Get("ticker")
+
+	if isLookupPage(r) {
+		s.log.Info(ctx, "redirected to symbol lookup", "ticker", ticker, "url", r.Request.URL)
+		s.profileNotFound(r)
 		return
 	}
 
-	foundCountry := r.Ctx.Get("foundCountry")
-	if foundCountry == "" {
-		s.log.Error(ctx, "country not found", "ticker", r.Request.Ctx.Get("ticker"))
-		s.errorTickers = append(s.errorTickers, r.Request.Ctx.Get("ticker"))
+	var missingRequired []string
+	for _, field := range requiredProfileFields {
+		if r.Ctx.Get(foundFieldKey(field)) == "" {
+			s.log.Error(ctx, "required field not found", "ticker", ticker, "field", field)
+			missingRequired = append(missingRequired, field)
+		}
+	}
+
+	if len(missingRequired) > 0 {
+		s.failTicker(r.Request.Ctx, "required fields not found: "+strings.Join(missingRequired, ", "), r.StatusCode)
 		return
 	}
+
+	for _, field := range optionalProfileFields {
+		if r.Ctx.Get(foundFieldKey(field)) == "" {
+			s.log.Info(ctx, "optional field not found", "ticker", ticker, "field", field)
+		}
+	}
 }
 
-func (s *AssetProfileScraper) processAssetProfileResponse(e *colly.HTMLElement) {
+// processAssetProfileResponse delegates the page parsing to the profile extractor
+func (s *AssetProfileScraper) processAssetProfileResponse(r *colly.Response) {
 	// create correlation if for processing fund list
 	id, _ := uuid.NewRandom()
 	ctx := corid.NewContext(context.Background(), id)
 
-	ticker := e.Request.Ctx.Get("ticker")
+	ticker := r.Request.Ctx.Get("ticker")
 	s.log.Info(ctx, "processAssetProfileResponse", "ticker", ticker)
 
-	foundSector := false
-	foundCountry := false
+	// the lookup page has no profile, the ticker is reported not found once scraped
+	if isLookupPage(r) {
+		return
+	}
 
-	assetProfile := entities.AssetProfile{
-		Ticker: ticker,
+	extraction, err := ExtractFromHTML(s.extractor, r.Body, ticker)
+	if err != nil {
+		s.log.Error(ctx, "extract asset profile failed", "error", err, "ticker", ticker)
+		return
 	}
 
-	e.ForEach("p", func(_ int, paragraph *colly.HTMLElement) {
-		if foundCountry {
-			return
-		}
+	s.log.Info(ctx, "extracted asset profile", "ticker", ticker, "sources", extraction.Sources)
+	s.saveAssetProfile(ctx, r, extraction)
+}
 
-		var address []string
-		paragraph.DOM.Contents().Not("br").Not("a").Each(func(i int, n *goquery.Selection) {
-			if goquery.NodeName(n) == "#text" {
-				address = append(address, n.Text())
-			}
-		})
+// saveAssetProfile flags the found fields on the response and saves the asset profile
+// when all the required fields are found
+func (s *AssetProfileScraper) saveAssetProfile(ctx context.Context, r *colly.Response, extraction *Extraction) {
+	for field := range extraction.Sources {
+		r.Ctx.Put(foundFieldKey(field), "true")
+	}
 
-		if len(address) > 0 {
-			foundCountry = true
-			assetProfile.Country = address[len(address)-1]
-		}
+	if len(extraction.Missing(requiredProfileFields)) > 0 {
+		return
+	}
+
+	// the profile joins back to the assets by ticker
+	assetProfile := extraction.AssetProfile
+	assetProfile.YahooSymbol = r.Request.Ctx.Get("symbol")
+	assetProfile.YahooRegion = r.Request.Ctx.Get("region")
+
+	// a country that is not a country is a parse failure, not a profile to save
+	country, ok := NormalizeCountry(assetProfile.Country)
+	if !ok {
+		s.failTicker(r.Request.Ctx, fmt.Sprintf("country not recognized: %q", assetProfile.Country), r.StatusCode)
+		return
+	}
+	assetProfile.CountryRaw = assetProfile.Country
+	assetProfile.Country = country.Name
+	assetProfile.CountryCode = country.Alpha2
+	assetProfile.CountryAlpha3 = country.Alpha3
+
+	s.classifyAssetProfile(assetProfile)
+
+	unchanged, err := s.assetProfileService.AddAssetProfile(ctx, assetProfile)
+	if err != nil {
+		s.log.Error(ctx, "add asset profile failed", "error", err, "ticker", assetProfile.Ticker)
+		s.failTicker(r.Request.Ctx, "add asset profile failed: "+err.Error(), r.StatusCode)
+	} else {
+		s.succeedTicker(r.Request.Ctx, unchanged)
+	}
+}
+
+// classifyAssetProfile sets the GICS-like classification of the Yahoo sector and industry of
+// the profile when a sector service is configured, and records the values left unmapped
+func (s *AssetProfileScraper) classifyAssetProfile(assetProfile *entities.AssetProfile) {
+	if s.classifier == nil {
+		return
+	}
+
+	classification, sectorMapped, industryMapped := s.classifier.Classify(assetProfile.Sector, assetProfile.Industry)
+
+	var unmappedSector, unmappedIndustry string
+	if !sectorMapped {
+		unmappedSector = assetProfile.Sector
+	}
+	if !industryMapped {
+		unmappedIndustry = assetProfile.Industry
+	}
+	s.run.unmapped(unmappedSector, unmappedIndustry)
+
+	if classification == nil {
+		return
+	}
+
+	assetProfile.GicsSectorCode = classification.SectorCode
+	assetProfile.GicsSector = classification.Sector
+	assetProfile.GicsIndustryGroupCode = classification.IndustryGroupCode
+	assetProfile.GicsIndustryGroup = classification.IndustryGroup
+	assetProfile.GicsIndustryCode = classification.IndustryCode
+	assetProfile.GicsIndustry = classification.Industry
+}
+
+// failTicker sends the failure of a ticker that could not be scraped to the results
+func (s *AssetProfileScraper) failTicker(reqCtx *colly.Context, reason string, statusCode int) {
+	ticker := reqCtx.Get("ticker")
+
+	s.results.send(scrapeResult{
+		ticker: ticker,
+		source: reqCtx.Get("source"),
+		failure: &entities.TickerFailure{
+			Ticker:     ticker,
+			Reason:     reason,
+			StatusCode: statusCode,
+			Attempts:   requestAttempt(reqCtx),
+		},
 	})
+}
 
-	e.ForEach("span", func(_ int, span *colly.HTMLElement) {
-		if foundSector {
-			return
-		}
+// notFoundTicker sends the failure of a ticker Yahoo does not know to the results
+func (s *AssetProfileScraper) notFoundTicker(reqCtx *colly.Context, statusCode int) {
+	ticker := reqCtx.Get("ticker")
+
+	s.results.send(scrapeResult{
+		ticker: ticker,
+		source: reqCtx.Get("source"),
+		failure: &entities.TickerFailure{
+			Ticker:     ticker,
+			Reason:     "ticker not found",
+			StatusCode: statusCode,
+			Attempts:   requestAttempt(reqCtx),
+		},
+		notFound: true,
+	})
+}
 
-		if strings.EqualFold(span.Text, "Sector(s)") {
-			firstSibling := span.DOM.Siblings().First()
-			profileSector := firstSibling.Text()
+// succeedTicker sends a scraped ticker to the results
+func (s *AssetProfileScraper) succeedTicker(reqCtx *colly.Context, unchanged bool) {
+	s.results.send(scrapeResult{
+		ticker:    reqCtx.Get("ticker"),
+		source:    reqCtx.Get("source"),
+		unchanged: unchanged,
+	})
+}
+
+// handleResult records the result of a ticker in the run. Failures are persisted when
+// a failed ticker service is configured, and successes clear the previous failures.
+// Not found tickers count towards soft deleting their stored profile
+func (s *AssetProfileScraper) handleResult(result scrapeResult) {
+	ctx := context.Background()
+
+	// the ticker is done, free its slot for the next one
+	defer func() { <-s.slots }()
 
-			if profileSector != "" {
-				foundSector = true
-				assetProfile.Sector = profileSector
+	if !result.succeeded() {
+		s.errorTickers = append(s.errorTickers, result.ticker)
+		s.run.failed(result.failure)
+
+		if result.notFound {
+			if err := s.assetProfileService.RecordNotFound(ctx, result.ticker); err != nil {
+				s.log.Error(ctx, "record ticker not found failed", "error", err, "ticker", result.ticker)
 			}
 		}
-	})
 
-	if foundSector && foundCountry {
-		e.Response.Ctx.Put("foundCountry", "true")
-		e.Response.Ctx.Put("foundSector", "true")
+		// failed tickers are drained by source, the ones scraped without a source would never be
+		if s.failedTickerService == nil || result.source == "" {
+			return
+		}
+
+		failedTicker := &entities.FailedTicker{
+			Ticker:     result.ticker,
+			Source:     result.source,
+			Reason:     result.failure.Reason,
+			StatusCode: result.failure.StatusCode,
+			Attempts:   result.failure.Attempts,
+		}
 
-		if err := s.assetProfileService.AddAssetProfile(ctx, &assetProfile); err != nil {
-			s.log.Error(ctx, "add asset profile failed", "error", err, "ticker", assetProfile.Ticker)
-			s.errorTickers = append(s.errorTickers, assetProfile.Ticker)
-		} else {
-			s.scrapedTickers = append(s.scrapedTickers, assetProfile.Ticker)
+		if err := s.failedTickerService.RecordFailure(ctx, failedTicker); err != nil {
+			s.log.Error(ctx, "record failed ticker failed", "error", err, "ticker", result.ticker)
 		}
+		return
+	}
+
+	s.scrapedTickers = append(s.scrapedTickers, result.ticker)
+	s.run.succeeded(result.ticker)
+	if result.unchanged {
+		s.run.unchanged()
+	}
+
+	if s.failedTickerService == nil || result.source == "" {
+		return
+	}
+
+	if err := s.failedTickerService.ClearFailure(ctx, result.ticker, result.source); err != nil {
+		s.log.Error(ctx, "clear failed ticker failed", "error", err, "ticker", result.ticker)
 	}
 }
 
-// Close scraper
+// Close scraper, returning the tickers scraped by all its runs
 func (s *AssetProfileScraper) Close() []string {
-	s.log.Info(context.Background(), "DONE - SCRAPING ASSET PROFILES", "errorTickers", s.errorTickers)
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	s.mu.Lock()
+	attempts := s.attempts
+	s.mu.Unlock()
+
+	s.log.Info(context.Background(), "DONE - SCRAPING ASSET PROFILES", "errorTickers", s.errorTickers, "retriedTickers", attempts)
 	return s.scrapedTickers
 }
+
+// isLookupPage reports whether Yahoo redirected the profile request to its symbol lookup page,
+// which it does for the tickers it does not know
+func isLookupPage(r *colly.Response) bool {
+	return strings.HasPrefix(r.Request.URL.Path, "/lookup")
+}
+
+// tickersOf returns the tickers of the assets
+func tickersOf(assets []*entities.Asset) []string {
+	var tickers []string
+	for _, asset := range assets {
+		tickers = append(tickers, asset.Ticker)
+	}
+	return tickers
+}
+
+// uniqueTickers removes the duplicated tickers keeping the first occurrence
+func uniqueTickers(tickers []string) []string {
+	seen := make(map[string]bool)
+
+	var unique []string
+	for _, ticker := range tickers {
+		if !seen[ticker] {
+			seen[ticker] = true
+			unique = append(unique, ticker)
+		}
+	}
+	return unique
+}
+
+// foundFieldKey returns the response context key flagging a field was found
+func foundFieldKey(field string) string {
+	return "found." + field
+}
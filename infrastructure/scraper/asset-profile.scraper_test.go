@@ -0,0 +1,1181 @@
+//go:build testing
+// +build testing
+
+package scraper
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/config"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/queue"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/repositories/repos"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/infrastructure/scraper/fakeyahoo"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/assets"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/checkpoint"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/failure"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/profile"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/report"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/sector"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/shard"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/symbol"
+)
+
+// testEnv wires a scraper to a fake Yahoo server and in-memory repos
+type testEnv struct {
+	server         *fakeyahoo.Server
+	assetRepo      *repos.AssetMemory
+	profileRepo    *repos.AssetProfileMemory
+	checkpointRepo *repos.CheckpointMemory
+	failedRepo     *repos.FailedTickerMemory
+	runReportRepo  *repos.RunReportMemory
+	log            logger.ContextLog
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	server := fakeyahoo.NewServer()
+
+	baseURL := config.YahooBaseURL
+	if err := config.SetYahooBaseURL(server.URL); err != nil {
+		t.Fatalf("set yahoo base url: %v", err)
+	}
+
+	zap, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+
+	t.Cleanup(func() {
+		server.Close()
+		config.YahooBaseURL = baseURL
+		zap.Close()
+	})
+
+	env := &testEnv{
+		server:         server,
+		assetRepo:      repos.NewAssetMemory(zap),
+		profileRepo:    repos.NewAssetProfileMemory(zap),
+		checkpointRepo: repos.NewCheckpointMemory(zap),
+		failedRepo:     repos.NewFailedTickerMemory(zap),
+		runReportRepo:  repos.NewRunReportMemory(zap),
+		log:            zap,
+	}
+	env.assetRepo.JoinProfiles(env.profileRepo, env.failedRepo)
+
+	return env
+}
+
+func (e *testEnv) newScraper(opts ...ScraperOption) *AssetProfileScraper {
+	checkpointService := checkpoint.NewService(e.checkpointRepo, e.log)
+	assetService := assets.NewService(e.assetRepo, *checkpointService, e.log)
+	profileService := profile.NewService(e.profileRepo, consts.DELETE_AFTER_NOT_FOUND, e.log)
+
+	// retry quickly and without the random delay of the testing config, tests can still override both
+	opts = append([]ScraperOption{WithRetryPolicy(testRetryPolicy()), WithRandomDelay(config.AppConf.Yahoo.RandomDelay())}, opts...)
+
+	return NewAssetProfileScraper(assetService, profileService, e.log, opts...)
+}
+
+func testRetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 5 * time.Millisecond
+	return policy
+}
+
+// storedProfile returns the asset profile saved for the ticker
+func (e *testEnv) storedProfile(ticker string) (entities.AssetProfile, bool) {
+	m, ok := e.profileRepo.FindAssetProfileModel(ticker)
+	if !ok {
+		return entities.AssetProfile{}, false
+	}
+
+	return entities.AssetProfile{
+		Ticker:            m.Ticker,
+		Sector:            m.Sector,
+		Industry:          m.Industry,
+		FullTimeEmployees: m.FullTimeEmployees,
+		Website:           m.Website,
+		Phone:             m.Phone,
+		Street:            m.Street,
+		City:              m.City,
+		Province:          m.Province,
+		PostalCode:        m.PostalCode,
+		Country:           m.Country,
+	}, true
+}
+
+func (e *testEnv) addAssets(source string, tickers ...string) {
+	for _, ticker := range tickers {
+		e.assetRepo.AddAssets(source, &entities.Asset{Ticker: ticker})
+	}
+}
+
+func sorted(tickers []string) []string {
+	s := append([]string{}, tickers...)
+	sort.Strings(s)
+	return s
+}
+
+func TestScrapeAssetProfilesByTickers(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.server.Script("AAPL", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("RY", fakeyahoo.StatePage(&royalBank))
+	env.server.Script("SLOW", fakeyahoo.Slow(fakeyahoo.ProfilePage(&entities.AssetProfile{Sector: "Energy", Country: "Canada"}), 200*time.Millisecond))
+	env.server.Script("GONE", fakeyahoo.NotFound())
+	env.server.Script("CONSENT", fakeyahoo.ConsentRedirect())
+	env.server.Script("LIMITED", fakeyahoo.TooManyRequests())
+
+	s := env.newScraper()
+	s.ScrapeAssetProfilesByTickers(context.Background(), []string{"AAPL", "RY", "SLOW", "GONE", "CONSENT", "LIMITED"})
+	scraped := s.Close()
+
+	if want := []string{"AAPL", "RY", "SLOW"}; !reflect.DeepEqual(sorted(scraped), want) {
+		t.Errorf("scraped tickers = %v, want %v", sorted(scraped), want)
+	}
+
+	if want := []string{"CONSENT", "GONE", "LIMITED"}; !reflect.DeepEqual(sorted(s.errorTickers), want) {
+		t.Errorf("error tickers = %v, want %v", sorted(s.errorTickers), want)
+	}
+
+	for _, want := range []entities.AssetProfile{apple, royalBank} {
+		got, ok := env.storedProfile(want.Ticker)
+		if !ok {
+			t.Errorf("profile %s not saved", want.Ticker)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("profile %s = %+v, want %+v", want.Ticker, got, want)
+		}
+	}
+
+	for _, ticker := range []string{"GONE", "CONSENT", "LIMITED"} {
+		if _, ok := env.storedProfile(ticker); ok {
+			t.Errorf("profile %s should not be saved", ticker)
+		}
+	}
+}
+
+func TestScrapeRetriesTransientFailures(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.server.Script("FLAKY", fakeyahoo.TooManyRequests(), fakeyahoo.ServiceUnavailable(), fakeyahoo.ProfilePage(&apple))
+	env.server.Script("LIMITED", fakeyahoo.TooManyRequests())
+	env.server.Script("GONE", fakeyahoo.NotFound())
+
+	s := env.newScraper()
+	s.ScrapeAssetProfilesByTickers(context.Background(), []string{"FLAKY", "LIMITED", "GONE"})
+	scraped := s.Close()
+
+	if want := []string{"FLAKY"}; !reflect.DeepEqual(scraped, want) {
+		t.Errorf("scraped tickers = %v, want %v", scraped, want)
+	}
+
+	if want := []string{"GONE", "LIMITED"}; !reflect.DeepEqual(sorted(s.errorTickers), want) {
+		t.Errorf("error tickers = %v, want %v", sorted(s.errorTickers), want)
+	}
+
+	hits := map[string]int{"FLAKY": 3, "LIMITED": 3, "GONE": 1}
+	for ticker, want := range hits {
+		if got := env.server.Hits(ticker); got != want {
+			t.Errorf("%s requested %d times, want %d", ticker, got, want)
+		}
+	}
+
+	if want := map[string]int{"FLAKY": 3, "LIMITED": 3}; !reflect.DeepEqual(s.attempts, want) {
+		t.Errorf("attempts = %v, want %v", s.attempts, want)
+	}
+}
+
+func TestScrapeAllAssetProfilesBySource(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.addAssets(consts.TIP_RANK_SOURCE, "AAPL", "RY")
+	env.addAssets("OTHER", "MSFT")
+
+	env.server.Script("AAPL", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("RY", fakeyahoo.ProfilePage(&royalBank))
+	env.server.Script("MSFT", fakeyahoo.ProfilePage(&apple))
+
+	s := env.newScraper()
+	s.ScrapeAllAssetProfilesBySource(context.Background(), "tip_rank")
+	scraped := s.Close()
+
+	if want := []string{"AAPL", "RY"}; !reflect.DeepEqual(sorted(scraped), want) {
+		t.Errorf("scraped tickers = %v, want %v", sorted(scraped), want)
+	}
+
+	if hits := env.server.Hits("MSFT"); hits != 0 {
+		t.Errorf("MSFT of another source was requested %d times", hits)
+	}
+}
+
+func TestScrapeAssetProfilesBySourceFromCheckpoint(t *testing.T) {
+	env := newTestEnv(t)
+
+	tickers := []string{"A", "B", "C", "D", "E"}
+	env.addAssets(consts.TIP_RANK_SOURCE, tickers...)
+	for _, ticker := range tickers {
+		env.server.Script(ticker, fakeyahoo.ProfilePage(&entities.AssetProfile{Sector: "Utilities", Country: "Canada"}))
+	}
+
+	// assets are paged newest first and the checkpoint wraps around after the last page
+	pages := [][]string{
+		{"D", "E"},
+		{"B", "C"},
+		{"A"},
+		{"D", "E"},
+	}
+
+	for i, want := range pages {
+		s := env.newScraper()
+		s.ScrapeAssetProfilesBySourceFromCheckpoint(context.Background(), consts.TIP_RANK_SOURCE, 2)
+		scraped := s.Close()
+
+		if !reflect.DeepEqual(sorted(scraped), want) {
+			t.Errorf("run %d scraped tickers = %v, want %v", i, sorted(scraped), want)
+		}
+	}
+}
+
+func (e *testEnv) failedTickers(source string) []string {
+	failedTickers, _ := e.failedRepo.FindFailedTickersBySource(context.Background(), source, 0)
+
+	var tickers []string
+	for _, failedTicker := range failedTickers {
+		tickers = append(tickers, failedTicker.Ticker)
+	}
+	return sorted(tickers)
+}
+
+// stopOnArrival cancels the run once n requests are held at the gate and then releases
+// them, so the run stops while exactly those requests are in flight
+func stopOnArrival(gate *fakeyahoo.Gate, n int, cancel context.CancelFunc) {
+	go func() {
+		defer gate.Release()
+		defer cancel()
+
+		timeout := time.After(10 * time.Second)
+		for i := 0; i < n; i++ {
+			select {
+			case <-gate.Arrived():
+			case <-timeout:
+				return
+			}
+		}
+	}()
+}
+
+func TestScrapeRecordsAndDrainsFailedTickers(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.addAssets(consts.TIP_RANK_SOURCE, "A", "B", "C")
+	env.server.Script("A", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("B", fakeyahoo.NotFound(), fakeyahoo.ProfilePage(&royalBank))
+	env.server.Script("C", fakeyahoo.ProfilePage(&entities.AssetProfile{Industry: "Banks"}))
+
+	failedTickerService := failure.NewService(env.failedRepo, 2, env.log)
+	newScraper := func() *AssetProfileScraper {
+		return env.newScraper(WithFailedTickerService(failedTickerService), WithFailedTickerDrain(10))
+	}
+
+	// first run fails B with a 404 and C with missing required fields
+	s := newScraper()
+	s.ScrapeAllAssetProfilesBySource(context.Background(), consts.TIP_RANK_SOURCE)
+	s.Close()
+
+	if got, want := env.failedTickers(consts.TIP_RANK_SOURCE), []string{"B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("failed tickers = %v, want %v", got, want)
+	}
+
+	// second run drains the failed tickers only, B recovers and C is quarantined
+	s = newScraper()
+	s.ScrapeFailedAssetProfiles(context.Background(), consts.TIP_RANK_SOURCE, 10)
+	scraped := s.Close()
+
+	if want := []string{"B"}; !reflect.DeepEqual(scraped, want) {
+		t.Errorf("scraped tickers = %v, want %v", scraped, want)
+	}
+
+	if got := env.failedTickers(consts.TIP_RANK_SOURCE); len(got) != 0 {
+		t.Errorf("failed tickers = %v, want none left to drain", got)
+	}
+
+	if hits := env.server.Hits("A"); hits != 1 {
+		t.Errorf("A requested %d times, want 1", hits)
+	}
+
+	// quarantined tickers are not drained anymore
+	s = newScraper()
+	s.ScrapeFailedAssetProfiles(context.Background(), consts.TIP_RANK_SOURCE, 10)
+	s.Close()
+
+	if hits := env.server.Hits("C"); hits != 2 {
+		t.Errorf("C requested %d times, want 2", hits)
+	}
+}
+
+func TestScrapeByTickersDoesNotRecordFailedTickers(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.server.Script("B", fakeyahoo.NotFound())
+
+	failedTickerService := failure.NewService(env.failedRepo, 0, env.log)
+	s := env.newScraper(WithFailedTickerService(failedTickerService))
+	defer s.Close()
+
+	runReport := s.ScrapeAssetProfilesByTickers(context.Background(), []string{"B"})
+	if runReport.Failed != 1 {
+		t.Fatalf("failed = %d, want 1", runReport.Failed)
+	}
+
+	// without a source the failure would never be drained nor cleared
+	if got := env.failedTickers(""); len(got) != 0 {
+		t.Errorf("failed tickers = %v, want none", got)
+	}
+}
+
+func TestScrapeKeepsTheFailureOfTickersNotAttempted(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.server.Script("FLAKY", fakeyahoo.ServiceUnavailable())
+
+	failedTickerService := failure.NewService(env.failedRepo, 0, env.log)
+	s := env.newScraper(WithFailedTickerService(failedTickerService))
+	defer s.Close()
+
+	s.ScrapeAssetProfilesBySourceTickers(context.Background(), consts.TIP_RANK_SOURCE, []string{"FLAKY"})
+
+	failed, ok := env.failedRepo.FindFailedTickerModel("FLAKY", consts.TIP_RANK_SOURCE)
+	if !ok || failed.StatusCode != 503 || failed.Attempts != 3 {
+		t.Fatalf("failed ticker = %+v, want FLAKY failed with 503 after 3 attempts", failed)
+	}
+
+	// the next run stops before requesting FLAKY
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runReport := s.ScrapeAssetProfilesBySourceTickers(ctx, consts.TIP_RANK_SOURCE, []string{"FLAKY"})
+	if runReport.NotAttempted != 1 {
+		t.Fatalf("run report = %+v, want FLAKY not attempted", runReport)
+	}
+
+	got, _ := env.failedRepo.FindFailedTickerModel("FLAKY", consts.TIP_RANK_SOURCE)
+	if got.Reason != failed.Reason || got.StatusCode != 503 || got.Attempts != 3 || got.LastFailedAt != failed.LastFailedAt || got.FailureCount != 1 || !got.NotAttempted {
+		t.Errorf("failed ticker = %+v, want the 503 failure kept", got)
+	}
+}
+
+func TestScrapeBySourceTickersRecordsFailedTickers(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.server.Script("A", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("B", fakeyahoo.NotFound(), fakeyahoo.ProfilePage(&royalBank))
+
+	failedTickerService := failure.NewService(env.failedRepo, 0, env.log)
+	s := env.newScraper(WithFailedTickerService(failedTickerService))
+	defer s.Close()
+
+	s.ScrapeAssetProfilesBySourceTickers(context.Background(), consts.TIP_RANK_SOURCE, []string{"A", "B"})
+
+	if got, want := env.failedTickers(consts.TIP_RANK_SOURCE), []string{"B"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("failed tickers = %v, want %v", got, want)
+	}
+
+	// the drain of the source picks B up and clears it
+	runReport := s.ScrapeFailedAssetProfiles(context.Background(), consts.TIP_RANK_SOURCE, 10)
+	if want := []string{"B"}; !reflect.DeepEqual(runReport.ScrapedTickers, want) {
+		t.Errorf("scraped tickers = %v, want %v", runReport.ScrapedTickers, want)
+	}
+
+	if got := env.failedTickers(consts.TIP_RANK_SOURCE); len(got) != 0 {
+		t.Errorf("failed tickers = %v, want none left to drain", got)
+	}
+}
+
+func TestScrapeFromCheckpointDrainsFailedTickersFirst(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.addAssets(consts.TIP_RANK_SOURCE, "A", "B", "C")
+	for _, ticker := range []string{"A", "B", "C"} {
+		env.server.Script(ticker, fakeyahoo.ProfilePage(&apple))
+	}
+
+	ctx := context.Background()
+	env.failedRepo.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "A", Source: consts.TIP_RANK_SOURCE}, 0)
+	env.failedRepo.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "C", Source: consts.TIP_RANK_SOURCE}, 0)
+
+	failedTickerService := failure.NewService(env.failedRepo, 0, env.log)
+	s := env.newScraper(WithFailedTickerService(failedTickerService), WithFailedTickerDrain(10))
+	s.ScrapeAssetProfilesBySourceFromCheckpoint(context.Background(), consts.TIP_RANK_SOURCE, 2)
+	scraped := s.Close()
+
+	// the first page holds B and C, A comes from the failed tickers and C is requested once
+	if want := []string{"A", "B", "C"}; !reflect.DeepEqual(sorted(scraped), want) {
+		t.Errorf("scraped tickers = %v, want %v", sorted(scraped), want)
+	}
+
+	if hits := env.server.Hits("C"); hits != 1 {
+		t.Errorf("C requested %d times, want 1", hits)
+	}
+
+	if got := env.failedTickers(consts.TIP_RANK_SOURCE); len(got) != 0 {
+		t.Errorf("failed tickers = %v, want none", got)
+	}
+}
+
+func TestScrapeReturnsAndSavesRunReport(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.addAssets(consts.TIP_RANK_SOURCE, "A", "B", "C", "D")
+	env.server.Script("C", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("D", fakeyahoo.NotFound())
+
+	runReportService := report.NewService(env.runReportRepo, env.log)
+	s := env.newScraper(WithRunReportService(runReportService))
+
+	// the first page holds the two newest assets
+	runReport := s.ScrapeAssetProfilesBySourceFromCheckpoint(context.Background(), consts.TIP_RANK_SOURCE, 2)
+	s.Close()
+
+	if runReport.RunID == "" || runReport.Source != consts.TIP_RANK_SOURCE {
+		t.Errorf("run report = %+v, want a run id and the source", runReport)
+	}
+
+	if runReport.PageIndex != 0 || runReport.PageSize != 2 {
+		t.Errorf("checkpoint page = %d/%d, want 0/2", runReport.PageIndex, runReport.PageSize)
+	}
+
+	if runReport.Requested != 2 || runReport.Succeeded != 1 || runReport.Failed != 1 || runReport.Skipped != 0 {
+		t.Errorf("run report counts = %+v, want 2 requested, 1 succeeded and 1 failed", runReport)
+	}
+
+	if want := []string{"C"}; !reflect.DeepEqual(runReport.ScrapedTickers, want) {
+		t.Errorf("scraped tickers = %v, want %v", runReport.ScrapedTickers, want)
+	}
+
+	if len(runReport.Failures) != 1 || runReport.Failures[0].Ticker != "D" || runReport.Failures[0].StatusCode != 404 || runReport.Failures[0].Reason == "" {
+		t.Errorf("failures = %+v, want D with status 404 and a reason", runReport.Failures)
+	}
+
+	if runReport.FinishedAt < runReport.StartedAt || runReport.LatencyP95Ms < runReport.LatencyP50Ms {
+		t.Errorf("run report timings = %+v, want consistent timings", runReport)
+	}
+
+	saved := env.runReportRepo.FindAllRunReportModels()
+	if len(saved) != 1 || saved[0].RunID != runReport.RunID || saved[0].Failed != 1 {
+		t.Errorf("saved run reports = %+v, want the run report", saved)
+	}
+}
+
+func TestScraperIsReusableAcrossRuns(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.server.Script("AAPL", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("RY", fakeyahoo.ProfilePage(&royalBank))
+	env.server.Script("GONE", fakeyahoo.NotFound())
+
+	s := env.newScraper()
+
+	// runs started concurrently are serialized, and handlers are not stacked
+	var wg sync.WaitGroup
+	runReports := make([]*entities.RunReport, 3)
+	for i := range runReports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			runReports[i] = s.ScrapeAssetProfilesByTickers(context.Background(), []string{"AAPL", "RY", "GONE", "AAPL"})
+		}(i)
+	}
+	wg.Wait()
+	scraped := s.Close()
+
+	for i, runReport := range runReports {
+		if runReport.Requested != 3 || runReport.Succeeded != 2 || runReport.Failed != 1 {
+			t.Errorf("run %d report = %+v, want 3 requested, 2 succeeded and 1 failed", i, runReport)
+		}
+	}
+
+	if want := []string{"AAPL", "AAPL", "AAPL", "RY", "RY", "RY"}; !reflect.DeepEqual(sorted(scraped), want) {
+		t.Errorf("scraped tickers = %v, want %v", sorted(scraped), want)
+	}
+
+	for _, ticker := range []string{"AAPL", "RY", "GONE"} {
+		if hits := env.server.Hits(ticker); hits != 3 {
+			t.Errorf("%s requested %d times, want 3", ticker, hits)
+		}
+	}
+}
+
+func TestScrapeCountsUnchangedProfiles(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.server.Script("AAPL", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("RY", fakeyahoo.ProfilePage(&royalBank))
+
+	s := env.newScraper()
+	first := s.ScrapeAssetProfilesByTickers(ctx, []string{"AAPL", "RY"})
+
+	// Yahoo reclassifies RY between the runs
+	reclassified := royalBank
+	reclassified.Sector = "Banks"
+	env.server.Script("RY", fakeyahoo.ProfilePage(&reclassified))
+
+	second := s.ScrapeAssetProfilesByTickers(ctx, []string{"AAPL", "RY"})
+	s.Close()
+
+	if first.Unchanged != 0 || second.Succeeded != 2 || second.Unchanged != 1 {
+		t.Errorf("run reports = %+v and %+v, want AAPL unchanged in the second run only", first, second)
+	}
+
+	changes := env.profileRepo.FindProfileChangeModels("RY")
+	if len(changes) != 1 || changes[0].Field != "sector" || changes[0].NewValue != "Banks" {
+		t.Errorf("RY changes = %+v, want the sector change", changes)
+	}
+}
+
+func TestScrapeSoftDeletesNotFoundProfilesAndRevivesThem(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.server.Script("AAPL",
+		fakeyahoo.ProfilePage(&apple),
+		fakeyahoo.LookupRedirect("AAPL"),
+		fakeyahoo.NotFound(),
+		fakeyahoo.LookupRedirect("AAPL"),
+		fakeyahoo.ProfilePage(&apple),
+	)
+
+	s := env.newScraper()
+	defer s.Close()
+
+	s.ScrapeAssetProfilesByTickers(ctx, []string{"AAPL"})
+
+	for i := 1; i <= consts.DELETE_AFTER_NOT_FOUND; i++ {
+		runReport := s.ScrapeAssetProfilesByTickers(ctx, []string{"AAPL"})
+		if runReport.Failed != 1 || runReport.Failures[0].Reason != "ticker not found" {
+			t.Fatalf("not found run %d report = %+v, want AAPL not found", i, runReport)
+		}
+
+		m, _ := env.profileRepo.FindAssetProfileModel("AAPL")
+		if want := i == consts.DELETE_AFTER_NOT_FOUND; m.Deleted != want || m.Enabled == want || m.NotFoundCount != int64(i) {
+			t.Errorf("profile after %d not found = %+v, want deleted %v", i, m, want)
+		}
+	}
+
+	s.ScrapeAssetProfilesByTickers(ctx, []string{"AAPL"})
+
+	m, _ := env.profileRepo.FindAssetProfileModel("AAPL")
+	if m.Deleted || !m.Enabled || m.NotFoundCount != 0 {
+		t.Errorf("revived profile = %+v, want enabled and not deleted", m)
+	}
+
+	var deletions []interface{}
+	for _, change := range env.profileRepo.FindProfileChangeModels("AAPL") {
+		if change.Field == "deleted" {
+			deletions = append(deletions, change.NewValue)
+		}
+	}
+	if want := []interface{}{true, false}; !reflect.DeepEqual(deletions, want) {
+		t.Errorf("deleted changes = %v, want %v", deletions, want)
+	}
+}
+
+func TestScrapeRequestsYahooSymbols(t *testing.T) {
+	env := newTestEnv(t)
+
+	overrides := repos.NewSymbolOverrideMemory(env.log)
+	overrides.AddSymbolOverride("TSE:ODD.UN", "ODD-UN.TO")
+
+	env.server.Script("RY.TO", fakeyahoo.ProfilePage(&royalBank))
+	env.server.Script("BRK-B", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("ODD-UN.TO", fakeyahoo.ProfilePage(&apple))
+
+	s := env.newScraper(WithSymbolService(symbol.NewService(overrides, env.log)))
+	s.ScrapeAssetProfilesByTickers(context.Background(), []string{"TSE:RY", "BRK.B", "TSE:ODD.UN"})
+	scraped := s.Close()
+
+	if want := []string{"BRK.B", "TSE:ODD.UN", "TSE:RY"}; !reflect.DeepEqual(sorted(scraped), want) {
+		t.Errorf("scraped tickers = %v, want %v", sorted(scraped), want)
+	}
+
+	// the profiles are stored by ticker, along with the symbol they were requested by
+	for ticker, want := range map[string]string{"TSE:RY": "RY.TO", "BRK.B": "BRK-B", "TSE:ODD.UN": "ODD-UN.TO"} {
+		m, ok := env.profileRepo.FindAssetProfileModel(ticker)
+		if !ok || m.YahooSymbol != want {
+			t.Errorf("profile %s = %+v, want yahoo symbol %s", ticker, m, want)
+		}
+	}
+}
+
+func TestScrapeNormalizesCountries(t *testing.T) {
+	env := newTestEnv(t)
+
+	usa := apple
+	usa.Country = "United States of America"
+	garbled := royalBank
+	garbled.Country = "M5J 2J5"
+
+	env.server.Script("AAPL", fakeyahoo.ProfilePage(&usa))
+	env.server.Script("RY", fakeyahoo.ProfilePage(&garbled))
+
+	s := env.newScraper()
+	runReport := s.ScrapeAssetProfilesByTickers(context.Background(), []string{"AAPL", "RY"})
+	s.Close()
+
+	m, ok := env.profileRepo.FindAssetProfileModel("AAPL")
+	if !ok || m.Country != "United States" || m.CountryRaw != "United States of America" || m.CountryCode != "US" || m.CountryAlpha3 != "USA" {
+		t.Errorf("profile AAPL = %+v, want the normalized and the raw country", m)
+	}
+
+	// a country that is not a country fails the ticker instead of being saved
+	if runReport.Failed != 1 || runReport.Failures[0].Ticker != "RY" || runReport.Failures[0].Reason != `country not recognized: "M5J 2J5"` {
+		t.Errorf("run report = %+v, want RY failed on its country", runReport)
+	}
+	if _, ok := env.storedProfile("RY"); ok {
+		t.Errorf("profile RY should not be saved")
+	}
+}
+
+func TestScrapeClassifiesSectors(t *testing.T) {
+	env := newTestEnv(t)
+
+	mappings := repos.NewSectorMappingMemory(env.log)
+	mappings.AddSectorMapping("", "Crypto Mining", "402030")
+
+	miner := royalBank
+	miner.Ticker, miner.Sector, miner.Industry = "HUT", "Financial Services", "Crypto Mining"
+	odd := royalBank
+	odd.Ticker, odd.Sector, odd.Industry = "ODD", "Other", "Lending Circles"
+
+	env.server.Script("AAPL", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("RY", fakeyahoo.ProfilePage(&royalBank))
+	env.server.Script("HUT", fakeyahoo.ProfilePage(&miner))
+	env.server.Script("ODD", fakeyahoo.ProfilePage(&odd))
+
+	s := env.newScraper(WithSectorService(sector.NewService(mappings, env.log)))
+	runReport := s.ScrapeAssetProfilesByTickers(context.Background(), []string{"AAPL", "RY", "HUT", "ODD"})
+	s.Close()
+
+	// the raw Yahoo values are kept alongside the classification
+	for ticker, want := range map[string][]string{
+		"AAPL": {"Technology", "45", "4520", "452020"},
+		"RY":   {"Financial Services", "40", "4010", "401010"},
+		"HUT":  {"Financial Services", "40", "4020", "402030"},
+		"ODD":  {"Other", "", "", ""},
+	} {
+		m, ok := env.profileRepo.FindAssetProfileModel(ticker)
+		if got := []string{m.Sector, m.GicsSectorCode, m.GicsIndustryGroupCode, m.GicsIndustryCode}; !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("profile %s classification = %v, want %v", ticker, got, want)
+		}
+	}
+
+	if runReport.Succeeded != 4 || !reflect.DeepEqual(runReport.UnmappedSectors, []string{"Other"}) || !reflect.DeepEqual(runReport.UnmappedIndustries, []string{"Lending Circles"}) {
+		t.Errorf("run report = %+v, want the unmapped sector and industry of ODD", runReport)
+	}
+}
+
+func TestScrapeFallsBackAcrossYahooRegions(t *testing.T) {
+	env := newTestEnv(t)
+
+	www := fakeyahoo.NewServer()
+	defer www.Close()
+
+	sites := &entities.YahooSites{
+		URLTemplates: map[string]string{
+			"ca":  env.server.URL + config.ProfilePathTemplate,
+			"www": www.URL + config.ProfilePathTemplate,
+		},
+		Regions:           []string{"ca", "www"},
+		RegionsByExchange: map[string][]string{"NYSE": {"www"}},
+	}
+
+	// the ca site has RY and misses AAPL, which the www site has
+	env.server.Script("RY", fakeyahoo.ProfilePage(&royalBank))
+	env.server.Script("AAPL", fakeyahoo.LookupRedirect("AAPL"))
+	www.Script("AAPL", fakeyahoo.ProfilePage(&apple))
+	www.Script("IBM", fakeyahoo.ProfilePage(&apple))
+	env.server.Script("GONE", fakeyahoo.NotFound())
+	www.Script("GONE", fakeyahoo.NotFound())
+
+	s := env.newScraper(
+		WithYahooSites(sites),
+		WithSymbolService(symbol.NewService(repos.NewSymbolOverrideMemory(env.log), env.log)),
+	)
+	runReport := s.ScrapeAssetProfilesByTickers(context.Background(), []string{"RY", "AAPL", "NYSE:IBM", "GONE"})
+	s.Close()
+
+	for ticker, want := range map[string]string{"RY": "ca", "AAPL": "www", "NYSE:IBM": "www"} {
+		m, ok := env.profileRepo.FindAssetProfileModel(ticker)
+		if !ok || m.YahooRegion != want {
+			t.Errorf("profile %s = %+v, want yahoo region %s", ticker, m, want)
+		}
+	}
+
+	// the NYSE tickers go to the www site only, and the unknown tickers are missing everywhere
+	if hits := env.server.Hits("IBM"); hits != 0 {
+		t.Errorf("IBM requested %d times from the ca site, want 0", hits)
+	}
+	if hits := []int{env.server.Hits("GONE"), www.Hits("GONE")}; !reflect.DeepEqual(hits, []int{1, 1}) {
+		t.Errorf("GONE requested %v times from the ca and www sites, want once each", hits)
+	}
+	if runReport.Succeeded != 3 || runReport.Failed != 1 || runReport.Failures[0].Reason != "ticker not found" {
+		t.Errorf("run report = %+v, want GONE not found", runReport)
+	}
+}
+
+func TestScrapeDoesNotFallBackPastTheStop(t *testing.T) {
+	env := newTestEnv(t)
+
+	www := fakeyahoo.NewServer()
+	defer www.Close()
+
+	sites := &entities.YahooSites{
+		URLTemplates: map[string]string{
+			"ca":  env.server.URL + config.ProfilePathTemplate,
+			"www": www.URL + config.ProfilePathTemplate,
+		},
+		Regions: []string{"ca", "www"},
+	}
+
+	// the ca site loses AAPL while the second run is stopping
+	gate := fakeyahoo.NewGate()
+	defer gate.Release()
+	env.server.Script("AAPL", fakeyahoo.ProfilePage(&apple), fakeyahoo.Held(fakeyahoo.NotFound(), gate))
+	www.Script("AAPL", fakeyahoo.ProfilePage(&apple))
+
+	s := env.newScraper(WithYahooSites(sites))
+	defer s.Close()
+
+	s.ScrapeAssetProfilesByTickers(context.Background(), []string{"AAPL"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopOnArrival(gate, 1, cancel)
+	runReport := s.ScrapeAssetProfilesByTickers(ctx, []string{"AAPL"})
+
+	if runReport.Failed != 1 || runReport.Failures[0].Reason == "ticker not found" {
+		t.Errorf("run report = %+v, want AAPL failed without being not found", runReport)
+	}
+	if hits := www.Hits("AAPL"); hits != 0 {
+		t.Errorf("AAPL requested %d times from the www site, want 0", hits)
+	}
+
+	m, _ := env.profileRepo.FindAssetProfileModel("AAPL")
+	if m.NotFoundCount != 0 || m.Deleted {
+		t.Errorf("profile AAPL = %+v, want it not counted as not found", m)
+	}
+}
+
+func TestScrapeStopsBeforeTheDeadline(t *testing.T) {
+	env := newTestEnv(t)
+
+	tickers := []string{"A", "B", "C", "D", "E", "F"}
+	env.addAssets(consts.TIP_RANK_SOURCE, tickers...)
+
+	// assets are listed newest first, the run stops while C and D hold both slots
+	gate := fakeyahoo.NewGate()
+	defer gate.Release()
+	for _, ticker := range tickers {
+		page := fakeyahoo.ProfilePage(&apple)
+		if ticker == "C" || ticker == "D" {
+			page = fakeyahoo.Held(page, gate)
+		}
+		env.server.Script(ticker, page)
+	}
+
+	failedTickerService := failure.NewService(env.failedRepo, 1, env.log)
+	s := env.newScraper(WithFailedTickerService(failedTickerService))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopOnArrival(gate, 2, cancel)
+
+	runReport := s.ScrapeAllAssetProfilesBySource(ctx, consts.TIP_RANK_SOURCE)
+	s.Close()
+
+	if want := []string{"C", "D", "E", "F"}; !reflect.DeepEqual(sorted(runReport.ScrapedTickers), want) {
+		t.Errorf("scraped tickers = %v, want %v", sorted(runReport.ScrapedTickers), want)
+	}
+
+	if want := []string{"B", "A"}; runReport.NotAttempted != 2 || !reflect.DeepEqual(runReport.NotAttemptedTickers, want) {
+		t.Errorf("not attempted = %d %v, want %v", runReport.NotAttempted, runReport.NotAttemptedTickers, want)
+	}
+
+	for _, ticker := range []string{"A", "B"} {
+		if hits := env.server.Hits(ticker); hits != 0 {
+			t.Errorf("%s requested %d times, want 0", ticker, hits)
+		}
+	}
+
+	// the not attempted tickers are queued for the next run without counting as failures
+	failed, _ := env.failedRepo.FindFailedTickersBySource(context.Background(), consts.TIP_RANK_SOURCE, 0)
+	if len(failed) != 2 || failed[0].FailureCount != 0 || !failed[0].NotAttempted || failed[0].Quarantined {
+		t.Errorf("failed tickers = %+v, want A and B not attempted", failed)
+	}
+}
+
+func TestStopContextLeavesTheDeadlineMargin(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.newScraper(WithDeadlineMargin(10 * time.Minute))
+	defer s.Close()
+
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	stop, stopCancel := s.stopContext(ctx)
+	defer stopCancel()
+
+	if got, ok := stop.Deadline(); !ok || !got.Equal(deadline.Add(-10*time.Minute)) {
+		t.Errorf("stop deadline = %v, want %v", got, deadline.Add(-10*time.Minute))
+	}
+
+	// without a deadline the run only stops when the context is cancelled
+	stop, stopCancel = s.stopContext(context.Background())
+	defer stopCancel()
+
+	if _, ok := stop.Deadline(); ok {
+		t.Errorf("stop has a deadline, want none")
+	}
+}
+
+func TestScrapeByTickersStopsBeforeTheDeadlineWithoutRecordingTickers(t *testing.T) {
+	env := newTestEnv(t)
+
+	tickers := []string{"A", "B", "C", "D"}
+	for _, ticker := range tickers {
+		env.server.Script(ticker, fakeyahoo.ProfilePage(&apple))
+	}
+
+	failedTickerService := failure.NewService(env.failedRepo, 0, env.log)
+	s := env.newScraper(WithFailedTickerService(failedTickerService))
+
+	// the run is stopped before it starts
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runReport := s.ScrapeAssetProfilesByTickers(ctx, tickers)
+	s.Close()
+
+	if runReport.NotAttempted != 4 || !reflect.DeepEqual(runReport.NotAttemptedTickers, tickers) {
+		t.Fatalf("run report = %+v, want all tickers not attempted", runReport)
+	}
+
+	// without a source the not attempted tickers would never be drained nor cleared
+	if got := env.failedTickers(""); len(got) != 0 {
+		t.Errorf("failed tickers = %v, want none", got)
+	}
+}
+
+func TestScrapeFromCheckpointCommitsCompletePagesOnly(t *testing.T) {
+	env := newTestEnv(t)
+
+	tickers := []string{"A", "B", "C", "D"}
+	env.addAssets(consts.TIP_RANK_SOURCE, tickers...)
+
+	gate := fakeyahoo.NewGate()
+	defer gate.Release()
+	for _, ticker := range tickers {
+		env.server.Script(ticker, fakeyahoo.Held(fakeyahoo.ProfilePage(&apple), gate))
+	}
+
+	// the first page of three tickers can't complete, the run stops while two are in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	stopOnArrival(gate, 2, cancel)
+
+	s := env.newScraper()
+	runReport := s.ScrapeAssetProfilesBySourceFromCheckpoint(ctx, consts.TIP_RANK_SOURCE, 3)
+	s.Close()
+
+	if runReport.PageIndex != 0 || runReport.NotAttempted != 1 {
+		t.Fatalf("run report = %+v, want page 0 with one ticker not attempted", runReport)
+	}
+
+	// the abandoned page is claimed again, then completed and committed
+	for i, wantIndex := range []int64{0, 1} {
+		s = env.newScraper()
+		runReport = s.ScrapeAssetProfilesBySourceFromCheckpoint(context.Background(), consts.TIP_RANK_SOURCE, 3)
+		s.Close()
+
+		if runReport.PageIndex != wantIndex || runReport.NotAttempted != 0 {
+			t.Errorf("run %d report = %+v, want page %d complete", i, runReport, wantIndex)
+		}
+	}
+}
+
+func TestScrapeStaleAssetProfilesPicksDueAssets(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.addAssets(consts.TIP_RANK_SOURCE, "FRESH", "FAILED", "NEW")
+	for _, ticker := range []string{"FRESH", "FAILED"} {
+		env.profileRepo.UpsertAssetProfile(ctx, &entities.AssetProfile{Ticker: ticker, Sector: "Technology"})
+	}
+	env.failedRepo.UpsertFailedTicker(ctx, &entities.FailedTicker{Ticker: "FAILED", Source: consts.TIP_RANK_SOURCE, StatusCode: 503}, consts.QUARANTINE_AFTER_FAILURES)
+
+	for _, ticker := range []string{"FRESH", "FAILED", "NEW"} {
+		env.server.Script(ticker, fakeyahoo.ProfilePage(&apple))
+	}
+
+	s := env.newScraper(WithRefreshPolicy(&entities.RefreshPolicy{MaxAge: time.Hour}))
+	runReport := s.ScrapeStaleAssetProfilesBySource(ctx, consts.TIP_RANK_SOURCE, 10)
+	s.Close()
+
+	// the fresh profile is not due yet
+	if want := []string{"FAILED", "NEW"}; runReport.Requested != 2 || !reflect.DeepEqual(sorted(runReport.ScrapedTickers), want) {
+		t.Errorf("run report = %+v, want %v scraped", runReport, want)
+	}
+
+	if hits := env.server.Hits("FRESH"); hits != 0 {
+		t.Errorf("FRESH requested %d times, want 0", hits)
+	}
+}
+
+func TestScrapeFromCheckpointConcurrentRunsScrapeDistinctPages(t *testing.T) {
+	env := newTestEnv(t)
+
+	tickers := []string{"A", "B", "C", "D"}
+	env.addAssets(consts.TIP_RANK_SOURCE, tickers...)
+	for _, ticker := range tickers {
+		env.server.Script(ticker, fakeyahoo.ProfilePage(&apple))
+	}
+
+	reports := make([]*entities.RunReport, 2)
+
+	var wg sync.WaitGroup
+	for i := range reports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			s := env.newScraper()
+			reports[i] = s.ScrapeAssetProfilesBySourceFromCheckpoint(context.Background(), consts.TIP_RANK_SOURCE, 2)
+			s.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	scraped := append(append([]string{}, reports[0].ScrapedTickers...), reports[1].ScrapedTickers...)
+	if !reflect.DeepEqual(sorted(scraped), tickers) || reports[0].PageIndex == reports[1].PageIndex {
+		t.Errorf("run reports = %+v and %+v, want distinct pages covering %v", reports[0], reports[1], tickers)
+	}
+
+	for _, ticker := range tickers {
+		if hits := env.server.Hits(ticker); hits != 1 {
+			t.Errorf("%s requested %d times, want 1", ticker, hits)
+		}
+	}
+}
+
+func TestScrapeShardsCoversTheSourceOnce(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	tickers := []string{"A", "B", "C", "D", "E"}
+	env.addAssets(consts.TIP_RANK_SOURCE, tickers...)
+	for _, ticker := range append(tickers, "LATE") {
+		env.server.Script(ticker, fakeyahoo.ProfilePage(&apple))
+	}
+
+	shardRepo := repos.NewShardMemory(env.log)
+	checkpointService := checkpoint.NewService(env.checkpointRepo, env.log)
+	shardService := shard.NewService(shardRepo, assets.NewService(env.assetRepo, *checkpointService, env.log), env.log)
+
+	shardQueue := queue.NewShardMemoryQueue()
+	shards, err := shardService.CreateShards(ctx, consts.TIP_RANK_SOURCE, 2, shardQueue)
+	if err != nil || len(shards) != 3 {
+		t.Fatalf("create shards = %d shards, %v, want 3 shards", len(shards), err)
+	}
+
+	// assets added after the shards were created don't shift them, they fall in the first shard
+	env.addAssets(consts.TIP_RANK_SOURCE, "LATE")
+
+	var scraped []string
+	for sh, ok := shardQueue.Receive(); ok; sh, ok = shardQueue.Receive() {
+		s := env.newScraper(WithShardService(shardService))
+		runReport := s.ScrapeAssetProfilesByShard(ctx, sh)
+		s.Close()
+
+		scraped = append(scraped, runReport.ScrapedTickers...)
+	}
+
+	if want := append(tickers, "LATE"); !reflect.DeepEqual(sorted(scraped), want) {
+		t.Errorf("scraped = %v, want %v", sorted(scraped), want)
+	}
+
+	tracked := shardRepo.FindAllShardModels(shards[0].JobID)
+	if len(tracked) != 3 {
+		t.Fatalf("tracked shards = %d, want 3", len(tracked))
+	}
+	for _, m := range tracked {
+		if m.Status != consts.SHARD_DONE || m.Total != 3 || m.RunID == "" || m.CompletedAt == 0 {
+			t.Errorf("shard %d = %+v, want done with its run", m.Index, m)
+		}
+	}
+}
+
+func TestScrapeDoesNotRetryPastTheDeadline(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.server.Script("FLAKY", fakeyahoo.TooManyRequests(), fakeyahoo.ProfilePage(&apple))
+
+	// the backoff ends well past the deadline, however long the first request takes
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay, policy.MaxDelay = 10*time.Minute, 10*time.Minute
+	s := env.newScraper(WithRetryPolicy(policy), WithDeadlineMargin(0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	runReport := s.ScrapeAssetProfilesByTickers(ctx, []string{"FLAKY"})
+	s.Close()
+
+	if runReport.Failed != 1 || runReport.Failures[0].StatusCode != 429 {
+		t.Errorf("run report = %+v, want FLAKY failed without retry", runReport)
+	}
+
+	if hits := env.server.Hits("FLAKY"); hits != 1 {
+		t.Errorf("FLAKY requested %d times, want 1", hits)
+	}
+}
+
+func TestScrapeStopsDuringTheBackoff(t *testing.T) {
+	env := newTestEnv(t)
+
+	gate := fakeyahoo.NewGate()
+	defer gate.Release()
+	env.server.Script("FLAKY", fakeyahoo.Held(fakeyahoo.TooManyRequests(), gate), fakeyahoo.ProfilePage(&apple))
+
+	// without a deadline the backoff is never cut short by canRetry, only by the stop
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay, policy.MaxDelay = 10*time.Minute, 10*time.Minute
+	s := env.newScraper(WithRetryPolicy(policy))
+
+	// the stop lands before or during the backoff, either way the run ends without waiting it out
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-gate.Arrived()
+		gate.Release()
+		cancel()
+	}()
+
+	runReport := s.ScrapeAssetProfilesByTickers(ctx, []string{"FLAKY"})
+	s.Close()
+
+	if runReport.Failed != 1 || runReport.Failures[0].StatusCode != 429 {
+		t.Errorf("run report = %+v, want FLAKY failed without retry", runReport)
+	}
+
+	if hits := env.server.Hits("FLAKY"); hits != 1 {
+		t.Errorf("FLAKY requested %d times, want 1", hits)
+	}
+}
+
+func TestReplayFixtures(t *testing.T) {
+	env := newTestEnv(t)
+
+	// restore the real Yahoo host, replayed requests never leave the process
+	config.YahooBaseURL = "https://ca.finance.yahoo.com"
+
+	s := env.newScraper(WithReplayDir("testdata"))
+	s.ScrapeAssetProfilesByTickers(context.Background(), []string{"AAPL", "RY.TO", "UNKNOWN"})
+	scraped := s.Close()
+
+	if want := []string{"AAPL", "RY.TO"}; !reflect.DeepEqual(sorted(scraped), want) {
+		t.Errorf("scraped tickers = %v, want %v", sorted(scraped), want)
+	}
+
+	if want := []string{"UNKNOWN"}; !reflect.DeepEqual(s.errorTickers, want) {
+		t.Errorf("error tickers = %v, want %v", s.errorTickers, want)
+	}
+
+	got, _ := env.storedProfile("AAPL")
+	if want := apple; !reflect.DeepEqual(got, want) {
+		t.Errorf("profile AAPL = %+v, want %+v", got, want)
+	}
+
+	got, _ = env.storedProfile("RY.TO")
+	want := royalBank
+	want.Ticker = "RY.TO"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("profile RY.TO = %+v, want %+v", got, want)
+	}
+}
+
+func TestRecordKeysPagesByTickerAndRegion(t *testing.T) {
+	env := newTestEnv(t)
+	dir := t.TempDir()
+
+	// TSE:RY and RY.TO are both requested by the RY.TO symbol
+	env.server.Script("RY.TO", fakeyahoo.ProfilePage(&royalBank))
+
+	s := env.newScraper(WithRecordDir(dir), WithSymbolService(symbol.NewService(repos.NewSymbolOverrideMemory(env.log), env.log)))
+	s.ScrapeAssetProfilesByTickers(context.Background(), []string{"TSE:RY", "RY.TO"})
+	s.Close()
+
+	for _, ticker := range []string{"TSE:RY", "RY.TO"} {
+		if _, err := os.Stat(FixturePath(dir, ticker, config.DefaultYahooRegion)); err != nil {
+			t.Errorf("page of %s not recorded: %v", ticker, err)
+		}
+	}
+
+	// pages recorded before they were keyed by region are replayed to every region
+	legacy, err := ioutil.ReadFile(FixturePath("testdata", "AAPL", config.DefaultYahooRegion))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	if err := ioutil.WriteFile(FixturePath(dir, "AAPL", ""), legacy, 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	s = env.newScraper(WithReplayDir(dir), WithSymbolService(symbol.NewService(repos.NewSymbolOverrideMemory(env.log), env.log)))
+	s.ScrapeAssetProfilesByTickers(context.Background(), []string{"TSE:RY", "RY.TO", "AAPL"})
+	scraped := s.Close()
+
+	if want := []string{"AAPL", "RY.TO", "TSE:RY"}; !reflect.DeepEqual(sorted(scraped), want) {
+		t.Errorf("scraped tickers = %v, want %v", sorted(scraped), want)
+	}
+
+	if hits := env.server.Hits("RY.TO"); hits != 2 {
+		t.Errorf("RY.TO requested %d times, want 2", hits)
+	}
+}
+
+func TestRecordSkipsRedirectedPages(t *testing.T) {
+	env := newTestEnv(t)
+	dir := t.TempDir()
+
+	env.server.Script("GONE", fakeyahoo.LookupRedirect("GONE"))
+	env.server.Script("WALL", fakeyahoo.ConsentRedirect())
+
+	s := env.newScraper(WithRecordDir(dir), WithRetryPolicy(NoRetryPolicy()))
+	s.ScrapeAssetProfilesByTickers(context.Background(), []string{"GONE", "WALL"})
+	s.Close()
+
+	// the lookup and consent pages are not the profile pages of the tickers
+	for _, ticker := range []string{"GONE", "WALL"} {
+		if _, err := os.Stat(FixturePath(dir, ticker, config.DefaultYahooRegion)); !os.IsNotExist(err) {
+			t.Errorf("page of %s recorded, want it skipped", ticker)
+		}
+	}
+
+	s = env.newScraper(WithReplayDir(dir))
+	runReport := s.ScrapeAssetProfilesByTickers(context.Background(), []string{"GONE"})
+	s.Close()
+
+	if runReport.Failed != 1 || runReport.Failures[0].Reason != "ticker not found" {
+		t.Errorf("run report = %+v, want GONE not found", runReport)
+	}
+}
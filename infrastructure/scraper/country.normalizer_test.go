@@ -0,0 +1,34 @@
+package scraper
+
+import "testing"
+
+func TestNormalizeCountry(t *testing.T) {
+	tests := []struct {
+		raw    string
+		alpha2 string
+		alpha3 string
+		name   string
+	}{
+		{raw: "United States", alpha2: "US", alpha3: "USA", name: "United States"},
+		{raw: "United States of America", alpha2: "US", alpha3: "USA", name: "United States"},
+		{raw: " canada ", alpha2: "CA", alpha3: "CAN", name: "Canada"},
+		{raw: "UK", alpha2: "GB", alpha3: "GBR", name: "United Kingdom"},
+		{raw: "Korea, Republic of", alpha2: "KR", alpha3: "KOR", name: "South Korea"},
+		{raw: "The Bahamas", alpha2: "BS", alpha3: "BHS", name: "Bahamas"},
+		{raw: "Curaçao", alpha2: "CW", alpha3: "CUW", name: "Curacao"},
+		{raw: "Bosnia & Herzegovina", alpha2: "BA", alpha3: "BIH", name: "Bosnia and Herzegovina"},
+	}
+
+	for _, test := range tests {
+		got, ok := NormalizeCountry(test.raw)
+		if !ok || got.Alpha2 != test.alpha2 || got.Alpha3 != test.alpha3 || got.Name != test.name {
+			t.Errorf("NormalizeCountry(%q) = %+v, %v, want %s %s %s", test.raw, got, ok, test.alpha2, test.alpha3, test.name)
+		}
+	}
+
+	for _, raw := range []string{"", "CA", "M5J 2J5", "416-974-5151", "Ontario", "the"} {
+		if got, ok := NormalizeCountry(raw); ok {
+			t.Errorf("NormalizeCountry(%q) = %+v, want not a country", raw, got)
+		}
+	}
+}
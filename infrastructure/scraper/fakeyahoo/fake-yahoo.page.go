@@ -0,0 +1,112 @@
+//go:build testing
+// +build testing
+
+package fakeyahoo
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// ProfilePage renders the asset profile in the div[data-test=qsp-profile] html layout
+func ProfilePage(p *entities.AssetProfile) Page {
+	var address []string
+	if p.Street != "" {
+		address = append(address, html.EscapeString(p.Street))
+	}
+
+	cityLine := html.EscapeString(p.City)
+	if region := strings.TrimSpace(p.Province + " " + p.PostalCode); region != "" {
+		cityLine = fmt.Sprintf("%s, %s", cityLine, html.EscapeString(region))
+	}
+	if cityLine != "" {
+		address = append(address, cityLine)
+	}
+
+	if p.Country != "" {
+		address = append(address, html.EscapeString(p.Country))
+	}
+
+	var b strings.Builder
+	b.WriteString(`<html><body><div data-test="qsp-profile"><div class="Mb(25px)">`)
+	b.WriteString(`<p class="D(ib) W(47.727%) Pend(40px)">`)
+	b.WriteString(strings.Join(address, " <br/>"))
+	if p.Phone != "" {
+		fmt.Fprintf(&b, `<br/><a href="tel:%s">%s</a>`, html.EscapeString(p.Phone), html.EscapeString(p.Phone))
+	}
+	if p.Website != "" {
+		fmt.Fprintf(&b, `<br/><a href="%s" target="_blank">%s</a>`, html.EscapeString(p.Website), html.EscapeString(p.Website))
+	}
+	b.WriteString(`</p><p class="D(ib) Va(t)">`)
+	if p.Sector != "" {
+		fmt.Fprintf(&b, `<span>Sector(s)</span>: <span class="Fw(600)">%s</span><br/>`, html.EscapeString(p.Sector))
+	}
+	if p.Industry != "" {
+		fmt.Fprintf(&b, `<span>Industry</span>: <span class="Fw(600)">%s</span><br/>`, html.EscapeString(p.Industry))
+	}
+	if p.FullTimeEmployees > 0 {
+		fmt.Fprintf(&b, `<span>Full Time Employees</span>: <span class="Fw(600)"><span>%s</span></span>`, formatThousands(p.FullTimeEmployees))
+	}
+	b.WriteString(`</p></div></div></body></html>`)
+
+	return Page{Status: http.StatusOK, Body: b.String()}
+}
+
+// StatePage renders the asset profile as the root.App.main JSON state with no html layout
+func StatePage(p *entities.AssetProfile) Page {
+	lines := strings.SplitN(p.Street, ", ", 2)
+
+	assetProfile := map[string]interface{}{
+		"address1":          lines[0],
+		"city":              p.City,
+		"state":             p.Province,
+		"zip":               p.PostalCode,
+		"country":           p.Country,
+		"phone":             p.Phone,
+		"website":           p.Website,
+		"industry":          p.Industry,
+		"sector":            p.Sector,
+		"fullTimeEmployees": p.FullTimeEmployees,
+	}
+	if len(lines) > 1 {
+		assetProfile["address2"] = lines[1]
+	}
+
+	state := map[string]interface{}{
+		"context": map[string]interface{}{
+			"dispatcher": map[string]interface{}{
+				"stores": map[string]interface{}{
+					"QuoteSummaryStore": map[string]interface{}{
+						"assetProfile": assetProfile,
+					},
+				},
+			},
+		},
+	}
+
+	data, _ := json.Marshal(state)
+
+	body := fmt.Sprintf("<html><head><script>(function (root) {\nroot.App || (root.App = {});\nroot.App.main = %s;\n}(this));</script></head><body><div id=\"app\"></div></body></html>", data)
+
+	return Page{Status: http.StatusOK, Body: body}
+}
+
+// LookupRedirect redirects to the symbol lookup page the way Yahoo does for unknown tickers
+func LookupRedirect(ticker string) Page {
+	return Page{Status: http.StatusFound, Redirect: "/lookup?s=" + url.QueryEscape(ticker)}
+}
+
+// formatThousands formats n with comma thousand separators
+func formatThousands(n int64) string {
+	s := fmt.Sprintf("%d", n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
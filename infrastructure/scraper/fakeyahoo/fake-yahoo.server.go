@@ -0,0 +1,229 @@
+//go:build testing
+// +build testing
+
+package fakeyahoo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Page is a scripted response of the fake server for a profile page
+type Page struct {
+	// Status is the http status code, 200 when zero
+	Status int
+	// Body is the html body
+	Body string
+	// Delay is how long to wait before answering
+	Delay time.Duration
+	// Redirect is the location the request is redirected to when set
+	Redirect string
+	// Gate holds the page until it is released when set
+	Gate *Gate
+}
+
+// NotFound answers with Yahoo's 404 page
+func NotFound() Page {
+	return Page{Status: http.StatusNotFound, Body: "<html><body><h1>Oops, something went wrong</h1></body></html>"}
+}
+
+// TooManyRequests answers with a 429 rate limit response
+func TooManyRequests() Page {
+	return Page{Status: http.StatusTooManyRequests, Body: "Too Many Requests"}
+}
+
+// ServiceUnavailable answers with a 503 response
+func ServiceUnavailable() Page {
+	return Page{Status: http.StatusServiceUnavailable, Body: "Service Unavailable"}
+}
+
+// ConsentRedirect redirects to the GDPR consent page, which has no profile
+func ConsentRedirect() Page {
+	return Page{Status: http.StatusFound, Redirect: "/consent?sessionId=fake"}
+}
+
+// Slow delays the page by d
+func Slow(page Page, d time.Duration) Page {
+	page.Delay = d
+	return page
+}
+
+// Held holds the page at the gate until the gate is released
+func Held(page Page, gate *Gate) Page {
+	page.Gate = gate
+	return page
+}
+
+// Gate holds the pages of requests until it is released, so tests can act while
+// the requests are in flight instead of racing them against the clock
+type Gate struct {
+	arrived  chan string
+	released chan struct{}
+	once     sync.Once
+}
+
+// NewGate creates new gate holding the requests until it is released
+func NewGate() *Gate {
+	return &Gate{
+		arrived:  make(chan string, 64),
+		released: make(chan struct{}),
+	}
+}
+
+// Arrived returns the tickers of the requests held at the gate as they come in
+func (g *Gate) Arrived() <-chan string {
+	return g.arrived
+}
+
+// Release lets the held requests through, and every request after them
+func (g *Gate) Release() {
+	g.once.Do(func() {
+		close(g.released)
+	})
+}
+
+// hold signals the arrival of a ticker's request and waits for the release,
+// reporting false when the client gave up on the request first. Requests
+// after the release pass without signalling
+func (g *Gate) hold(r *http.Request, ticker string) bool {
+	select {
+	case <-g.released:
+		return true
+	default:
+	}
+
+	select {
+	case g.arrived <- ticker:
+	case <-r.Context().Done():
+		return false
+	}
+
+	select {
+	case <-g.released:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// Server is a fake Yahoo Finance server serving scripted profile pages
+type Server struct {
+	*httptest.Server
+	mu    sync.Mutex
+	pages map[string][]Page
+	hits  map[string]int
+}
+
+// NewServer starts new fake Yahoo server. Callers must Close it
+func NewServer() *Server {
+	s := &Server{
+		pages: make(map[string][]Page),
+		hits:  make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/quote/", s.handleProfile)
+	mux.HandleFunc("/consent", s.handleConsent)
+	mux.HandleFunc("/lookup", s.handleLookup)
+
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Script sets the responses for a ticker's profile page. Each request consumes
+// the next page and the last page is repeated once the script runs out
+func (s *Server) Script(ticker string, pages ...Page) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pages[ticker] = pages
+	s.hits[ticker] = 0
+}
+
+// Hits returns how many times a ticker's profile page was requested
+func (s *Server) Hits(ticker string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hits[ticker]
+}
+
+// next returns the next scripted page for the ticker
+func (s *Server) next(ticker string) (Page, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pages, ok := s.pages[ticker]
+	if !ok || len(pages) == 0 {
+		return Page{}, false
+	}
+
+	hit := s.hits[ticker]
+	s.hits[ticker] = hit + 1
+
+	if hit >= len(pages) {
+		hit = len(pages) - 1
+	}
+
+	return pages[hit], true
+}
+
+// handleProfile serves /quote/{ticker}/profile
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[2] != "profile" {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, ok := s.next(parts[1])
+	if !ok {
+		page = NotFound()
+	}
+
+	if page.Gate != nil && !page.Gate.hold(r, parts[1]) {
+		return
+	}
+
+	if page.Delay > 0 {
+		select {
+		case <-time.After(page.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if page.Redirect != "" {
+		http.Redirect(w, r, page.Redirect, page.statusCode())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(page.statusCode())
+	fmt.Fprint(w, page.Body)
+}
+
+// handleConsent serves the consent page Yahoo redirects to in some regions
+func (s *Server) handleConsent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, `<html><body><form class="consent-form"><button name="agree">Accept all</button></form></body></html>`)
+}
+
+// handleLookup serves the symbol lookup page Yahoo redirects unknown tickers to
+func (s *Server) handleLookup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<html><body><section id="lookup-page"><h1>Symbol Lookup</h1><p>No results for '%s'</p></section></body></html>`, r.URL.Query().Get("s"))
+}
+
+// statusCode returns the page status defaulting to 200
+func (p Page) statusCode() int {
+	if p.Status == 0 {
+		return http.StatusOK
+	}
+	return p.Status
+}
@@ -0,0 +1,194 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fixture request headers the scraper keys the profile pages by. The Yahoo symbol in the
+// url may be shared by several tickers, and the same ticker is requested from several regions.
+// The path header holds the path of the requested profile page, the headers follow redirects
+const (
+	fixtureTickerHeader = "X-Fixture-Ticker"
+	fixtureRegionHeader = "X-Fixture-Region"
+	fixturePathHeader   = "X-Fixture-Path"
+)
+
+// ReplayTransport serves saved profile pages from a local directory instead of
+// hitting Yahoo. Pages are keyed by ticker and region, see FixturePath
+type ReplayTransport struct {
+	dir string
+}
+
+// NewReplayTransport creates new replay transport reading pages from dir
+func NewReplayTransport(dir string) *ReplayTransport {
+	return &ReplayTransport{
+		dir: dir,
+	}
+}
+
+// RoundTrip implements http.RoundTripper. Pages saved before they were keyed by
+// region are served to every region
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ticker, region := fixtureKey(req)
+	if ticker == "" {
+		return newFixtureResponse(req, http.StatusNotFound, nil), nil
+	}
+
+	body, err := ioutil.ReadFile(FixturePath(t.dir, ticker, region))
+	if os.IsNotExist(err) && region != "" {
+		body, err = ioutil.ReadFile(FixturePath(t.dir, ticker, ""))
+	}
+	if os.IsNotExist(err) {
+		return newFixtureResponse(req, http.StatusNotFound, nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newFixtureResponse(req, http.StatusOK, body), nil
+}
+
+// RecordTransport fetches pages with the next transport and saves every
+// successful profile page to a local directory so it can be replayed later
+type RecordTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+// NewRecordTransport creates new record transport writing pages to dir.
+// The http.DefaultTransport is used when next is nil
+func NewRecordTransport(dir string, next http.RoundTripper) *RecordTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &RecordTransport{
+		dir:  dir,
+		next: next,
+	}
+}
+
+// RoundTrip implements http.RoundTripper. The fixture headers are not sent on, and the pages
+// Yahoo redirects to, like the symbol lookup or the consent page, are not saved
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ticker, region := fixtureKey(req)
+	requested := isRequestedPage(req)
+
+	if req.Header.Get(fixtureTickerHeader) != "" {
+		req = req.Clone(req.Context())
+		req.Header.Del(fixtureTickerHeader)
+		req.Header.Del(fixtureRegionHeader)
+		req.Header.Del(fixturePathHeader)
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+
+	if ticker == "" || !requested {
+		return res, nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	path := FixturePath(t.dir, ticker, region)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(path, body, 0644); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// FixturePath returns the file a ticker's profile page from the site of a region is
+// saved to, {dir}/{region}/{ticker}.html. Pages without a region are saved to {dir}/{ticker}.html
+func FixturePath(dir string, ticker string, region string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, ticker)
+
+	if region == "" {
+		return filepath.Join(dir, fmt.Sprintf("%s.html", name))
+	}
+
+	return filepath.Join(dir, region, fmt.Sprintf("%s.html", name))
+}
+
+// fixtureHeader returns the headers keying the request of a ticker's profile page at path to a region
+func fixtureHeader(ticker string, region string, path string) http.Header {
+	return http.Header{
+		fixtureTickerHeader: []string{ticker},
+		fixtureRegionHeader: []string{region},
+		fixturePathHeader:   []string{path},
+	}
+}
+
+// isRequestedPage reports whether the request is for the profile page the scraper requested,
+// and not for a page Yahoo redirected it to. Requests without fixture headers are for a profile
+// page when their path is one
+func isRequestedPage(req *http.Request) bool {
+	if path := req.Header.Get(fixturePathHeader); path != "" {
+		return req.URL.Path == path
+	}
+
+	return strings.HasPrefix(req.URL.Path, "/quote/")
+}
+
+// fixtureKey returns the ticker and region of a profile page request from its fixture headers.
+// Requests without them are keyed by the ticker of their url and no region
+func fixtureKey(req *http.Request) (string, string) {
+	if ticker := req.Header.Get(fixtureTickerHeader); ticker != "" {
+		return ticker, req.Header.Get(fixtureRegionHeader)
+	}
+
+	return tickerFromURL(req), ""
+}
+
+// tickerFromURL gets the ticker of a profile page request from the p query
+// parameter, falling back to the /quote/{ticker}/profile path
+func tickerFromURL(req *http.Request) string {
+	if ticker := req.URL.Query().Get("p"); ticker != "" {
+		return ticker
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "quote" {
+		return parts[1]
+	}
+
+	return ""
+}
+
+// newFixtureResponse creates a html response for the request
+func newFixtureResponse(req *http.Request, statusCode int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
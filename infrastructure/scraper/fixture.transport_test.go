@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+)
+
+func TestRecordThenReplayProfilePages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("p") != "AAPL" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(appleProfilePage))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	get := func(transport http.RoundTripper, ticker string) (int, string) {
+		t.Helper()
+
+		res, err := (&http.Client{Transport: transport}).Get(server.URL + "/quote/" + ticker + "/profile?p=" + ticker)
+		if err != nil {
+			t.Fatalf("get %s: %v", ticker, err)
+		}
+		defer res.Body.Close()
+
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("read %s: %v", ticker, err)
+		}
+
+		return res.StatusCode, string(body)
+	}
+
+	record := NewRecordTransport(dir, nil)
+	for _, ticker := range []string{"AAPL", "GONE"} {
+		get(record, ticker)
+	}
+
+	// replayed pages never reach the server
+	server.Close()
+
+	replay := NewReplayTransport(dir)
+
+	if status, body := get(replay, "AAPL"); status != http.StatusOK || body != appleProfilePage {
+		t.Errorf("replayed AAPL = %d %q, want the recorded page", status, body)
+	}
+
+	// pages that were not found are not recorded
+	if status, _ := get(replay, "GONE"); status != http.StatusNotFound {
+		t.Errorf("replayed GONE status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestReplayDirTakesPrecedenceOverRecordDir(t *testing.T) {
+	zap, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer zap.Close()
+
+	orders := map[string][]ScraperOption{
+		"replay first": {WithReplayDir("replay"), WithRecordDir("record")},
+		"record first": {WithRecordDir("record"), WithReplayDir("replay")},
+	}
+
+	for name, opts := range orders {
+		s := NewAssetProfileScraper(nil, nil, zap, opts...)
+
+		if _, ok := s.fixtureTransport().(*ReplayTransport); !ok || !s.offline {
+			t.Errorf("%s: transport = %T offline %v, want a replay transport offline", name, s.fixtureTransport(), s.offline)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package scraper
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// HTMLExtractor extracts the asset profile from the div[data-test=qsp-profile] layout
+type HTMLExtractor struct{}
+
+// Name implements ProfileExtractor
+func (x *HTMLExtractor) Name() string {
+	return "html"
+}
+
+// Extract implements ProfileExtractor
+func (x *HTMLExtractor) Extract(doc *goquery.Document, ticker string) (*Extraction, error) {
+	profile := doc.Find("div[data-test=qsp-profile]").First()
+	if profile.Length() == 0 {
+		return nil, ErrProfileNotFound
+	}
+
+	assetProfile := entities.AssetProfile{
+		Ticker: ticker,
+	}
+
+	profile.Find("p").EachWithBreak(func(_ int, paragraph *goquery.Selection) bool {
+		var address []string
+		paragraph.Contents().Not("br").Not("a").Each(func(i int, n *goquery.Selection) {
+			if goquery.NodeName(n) == "#text" {
+				if line := strings.TrimSpace(n.Text()); line != "" {
+					address = append(address, line)
+				}
+			}
+		})
+
+		if len(address) == 0 {
+			return true
+		}
+
+		parseAddress(address, &assetProfile)
+
+		assetProfile.Phone = strings.TrimSpace(paragraph.Find("a[href^='tel:']").First().Text())
+		assetProfile.Website = strings.TrimSpace(paragraph.Find("a[href^='http']").First().AttrOr("href", ""))
+
+		return false
+	})
+
+	profile.Find("span").Each(func(_ int, span *goquery.Selection) {
+		value := strings.TrimSpace(span.NextAll().First().Text())
+		if value == "" {
+			return
+		}
+
+		label := span.Text()
+
+		switch {
+		case strings.EqualFold(label, "Sector(s)") && assetProfile.Sector == "":
+			assetProfile.Sector = value
+		case strings.EqualFold(label, "Industry") && assetProfile.Industry == "":
+			assetProfile.Industry = value
+		case strings.EqualFold(label, "Full Time Employees") && assetProfile.FullTimeEmployees == 0:
+			assetProfile.FullTimeEmployees = parseEmployees(value)
+		}
+	})
+
+	return newExtraction(x.Name(), &assetProfile), nil
+}
+
+// parseAddress fills the address fields of an asset profile from the address lines.
+// Yahoo lists the street lines first, then the city line and the country last. The city
+// line is "City, Province PostalCode" for US and CA listings, while other countries
+// often leave out the province, e.g. "London EC2V 7HN", "Paris, 75008" or "75008 Paris"
+func parseAddress(lines []string, assetProfile *entities.AssetProfile) {
+	if len(lines) == 0 {
+		return
+	}
+
+	assetProfile.Country = lines[len(lines)-1]
+
+	if len(lines) < 2 {
+		return
+	}
+
+	cityLine := lines[len(lines)-2]
+	assetProfile.Street = strings.Join(lines[:len(lines)-2], ", ")
+
+	comma := strings.LastIndex(cityLine, ",")
+	if comma < 0 {
+		assetProfile.City, assetProfile.PostalCode = splitPostalCode(strings.Fields(cityLine))
+		return
+	}
+
+	assetProfile.City = strings.TrimSpace(cityLine[:comma])
+	assetProfile.Province, assetProfile.PostalCode = splitPostalCode(strings.Fields(cityLine[comma+1:]))
+}
+
+// splitPostalCode splits the words of an address line into its name and postal code.
+// The postal code is the run of words holding a digit, either leading or trailing the name
+func splitPostalCode(words []string) (string, string) {
+	start := len(words)
+	for i, word := range words {
+		if hasDigit(word) {
+			start = i
+			break
+		}
+	}
+
+	if start > 0 {
+		return strings.Join(words[:start], " "), strings.Join(words[start:], " ")
+	}
+
+	end := 0
+	for end < len(words) && hasDigit(words[end]) {
+		end++
+	}
+
+	return strings.Join(words[end:], " "), strings.Join(words[:end], " ")
+}
+
+// hasDigit reports whether the word holds a digit
+func hasDigit(word string) bool {
+	return strings.IndexFunc(word, unicode.IsDigit) >= 0
+}
+
+// parseEmployees parses a formatted employee count such as "147,000"
+func parseEmployees(value string) int64 {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, value)
+
+	employees, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return employees
+}
@@ -0,0 +1,130 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// appleProfilePage is a profile page holding the asset profile in the div[data-test=qsp-profile] layout
+const appleProfilePage = `<!DOCTYPE html>
+<html lang="en-CA">
+<head><title>Apple Inc. (AAPL) Company Profile &amp; Facts - Yahoo Finance</title></head>
+<body>
+<div id="Main" role="content">
+<section class="Pb(30px) smartphone_Px(20px)" data-test="qsp-profile">
+</section>
+<div data-test="qsp-profile">
+<div class="Mb(25px)">
+<h3 class="Fz(m) Mb(10px)">Apple Inc.</h3>
+<div class="Mb(35px) smartphone_Mb(20px)">
+<p class="D(ib) W(47.727%) Pend(40px)">One Apple Park Way<br>Cupertino, CA 95014<br>United States<br><a href="tel:408 996 1010" class="C($linkColor)">408 996 1010</a><br><a href="http://www.apple.com" rel="noopener noreferrer" target="_blank" title="" class="C($linkColor)">http://www.apple.com</a></p>
+<p class="D(ib) Va(t)"><span>Sector(s)</span>: <span class="Fw(600)">Technology</span><br><span>Industry</span>: <span class="Fw(600)">Consumer Electronics</span><br><span>Full Time Employees</span>: <span class="Fw(600)"><span>147,000</span></span></p>
+</div>
+</div>
+</div>
+</div>
+</body>
+</html>`
+
+var apple = entities.AssetProfile{
+	Ticker:            "AAPL",
+	Sector:            "Technology",
+	Industry:          "Consumer Electronics",
+	FullTimeEmployees: 147000,
+	Website:           "http://www.apple.com",
+	Phone:             "408 996 1010",
+	Street:            "One Apple Park Way",
+	City:              "Cupertino",
+	Province:          "CA",
+	PostalCode:        "95014",
+	Country:           "United States",
+}
+
+func TestHTMLExtractor(t *testing.T) {
+	extraction, err := ExtractFromHTML(&HTMLExtractor{}, []byte(appleProfilePage), apple.Ticker)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+
+	if !reflect.DeepEqual(*extraction.AssetProfile, apple) {
+		t.Errorf("asset profile = %+v, want %+v", *extraction.AssetProfile, apple)
+	}
+
+	if missing := extraction.Missing(append(requiredProfileFields, optionalProfileFields...)); len(missing) > 0 {
+		t.Errorf("missing fields %v", missing)
+	}
+
+	if _, err := ExtractFromHTML(&HTMLExtractor{}, []byte(royalBankStatePage), royalBank.Ticker); err != ErrProfileNotFound {
+		t.Errorf("extract state layout error = %v, want %v", err, ErrProfileNotFound)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  entities.AssetProfile
+	}{
+		{
+			lines: []string{"100 King Street West", "Suite 5600", "Toronto, ON M5X 1A9", "Canada"},
+			want:  entities.AssetProfile{Street: "100 King Street West, Suite 5600", City: "Toronto", Province: "ON", PostalCode: "M5X 1A9", Country: "Canada"},
+		},
+		{
+			lines: []string{"One Apple Park Way", "Cupertino, CA 95014", "United States"},
+			want:  entities.AssetProfile{Street: "One Apple Park Way", City: "Cupertino", Province: "CA", PostalCode: "95014", Country: "United States"},
+		},
+		{
+			lines: []string{"25 Gresham Street", "London EC2V 7HN", "United Kingdom"},
+			want:  entities.AssetProfile{Street: "25 Gresham Street", City: "London", PostalCode: "EC2V 7HN", Country: "United Kingdom"},
+		},
+		{
+			lines: []string{"Edinburgh, EH2 4LH", "United Kingdom"},
+			want:  entities.AssetProfile{City: "Edinburgh", PostalCode: "EH2 4LH", Country: "United Kingdom"},
+		},
+		{
+			lines: []string{"54 rue La Boétie", "Paris, 75008", "France"},
+			want:  entities.AssetProfile{Street: "54 rue La Boétie", City: "Paris", PostalCode: "75008", Country: "France"},
+		},
+		{
+			lines: []string{"12 avenue Matignon", "75008 Paris", "France"},
+			want:  entities.AssetProfile{Street: "12 avenue Matignon", City: "Paris", PostalCode: "75008", Country: "France"},
+		},
+		{
+			lines: []string{"Taunusanlage 12", "Frankfurt am Main 60325", "Germany"},
+			want:  entities.AssetProfile{Street: "Taunusanlage 12", City: "Frankfurt am Main", PostalCode: "60325", Country: "Germany"},
+		},
+		{
+			lines: []string{"Hong Kong", "Hong Kong"},
+			want:  entities.AssetProfile{City: "Hong Kong", Country: "Hong Kong"},
+		},
+		{
+			lines: []string{"Bermuda"},
+			want:  entities.AssetProfile{Country: "Bermuda"},
+		},
+	}
+
+	for _, test := range tests {
+		var got entities.AssetProfile
+		parseAddress(test.lines, &got)
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseAddress(%q) = %+v, want %+v", test.lines, got, test.want)
+		}
+	}
+}
+
+func TestParseEmployees(t *testing.T) {
+	tests := map[string]int64{
+		"147,000": 147000,
+		"85301":   85301,
+		"N/A":     0,
+		"":        0,
+	}
+
+	for value, want := range tests {
+		if got := parseEmployees(value); got != want {
+			t.Errorf("parseEmployees(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
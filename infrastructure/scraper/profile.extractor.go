@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// ErrProfileNotFound is returned when no extractor finds any asset profile field
+var ErrProfileNotFound = errors.New("asset profile not found")
+
+// ProfileExtractor extracts an asset profile from a Yahoo profile page
+type ProfileExtractor interface {
+	// Name identifies the extractor in the field provenance
+	Name() string
+	// Extract returns the asset profile found in the document for the ticker
+	Extract(doc *goquery.Document, ticker string) (*Extraction, error)
+}
+
+// Extraction is an extracted asset profile with the provenance of each field
+type Extraction struct {
+	AssetProfile *entities.AssetProfile
+	// Sources maps each found field to the name of the extractor that found it
+	Sources map[string]string
+}
+
+// newExtraction creates an extraction crediting every found field to the extractor
+func newExtraction(name string, assetProfile *entities.AssetProfile) *Extraction {
+	extraction := &Extraction{
+		AssetProfile: assetProfile,
+		Sources:      make(map[string]string),
+	}
+
+	for _, field := range profileFields {
+		if field.found(assetProfile) {
+			extraction.Sources[field.name] = name
+		}
+	}
+
+	return extraction
+}
+
+// Found reports whether the field was extracted
+func (e *Extraction) Found(field string) bool {
+	_, ok := e.Sources[field]
+	return ok
+}
+
+// Missing returns the names of the fields that were not extracted
+func (e *Extraction) Missing(fields []string) []string {
+	var missing []string
+	for _, field := range fields {
+		if !e.Found(field) {
+			missing = append(missing, field)
+		}
+	}
+
+	return missing
+}
+
+// ExtractFromHTML parses raw html and extracts the asset profile with the extractor
+func ExtractFromHTML(extractor ProfileExtractor, html []byte, ticker string) (*Extraction, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+
+	return extractor.Extract(doc, ticker)
+}
+
+///////////////////////////////////////////////////////////
+// Extractor Chain
+///////////////////////////////////////////////////////////
+
+// ExtractorChain tries extractors in order. Fields missed by an extractor
+// are filled in by the ones after it
+type ExtractorChain struct {
+	extractors []ProfileExtractor
+}
+
+// NewExtractorChain creates new extractor chain
+func NewExtractorChain(extractors ...ProfileExtractor) *ExtractorChain {
+	return &ExtractorChain{
+		extractors: extractors,
+	}
+}
+
+// DefaultProfileExtractor prefers the embedded JSON state and falls back to the html layout
+func DefaultProfileExtractor() ProfileExtractor {
+	return NewExtractorChain(&StateExtractor{}, &HTMLExtractor{})
+}
+
+// Name implements ProfileExtractor
+func (c *ExtractorChain) Name() string {
+	return "chain"
+}
+
+// Extract implements ProfileExtractor
+func (c *ExtractorChain) Extract(doc *goquery.Document, ticker string) (*Extraction, error) {
+	result := &Extraction{
+		AssetProfile: &entities.AssetProfile{
+			Ticker: ticker,
+		},
+		Sources: make(map[string]string),
+	}
+
+	for _, extractor := range c.extractors {
+		extraction, err := extractor.Extract(doc, ticker)
+		if err != nil {
+			continue
+		}
+
+		for _, field := range profileFields {
+			if result.Found(field.name) || !extraction.Found(field.name) {
+				continue
+			}
+
+			field.copy(result.AssetProfile, extraction.AssetProfile)
+			result.Sources[field.name] = extraction.Sources[field.name]
+		}
+
+		if len(result.Sources) == len(profileFields) {
+			break
+		}
+	}
+
+	if len(result.Sources) == 0 {
+		return nil, ErrProfileNotFound
+	}
+
+	return result, nil
+}
+
+///////////////////////////////////////////////////////////
+// Profile Fields
+///////////////////////////////////////////////////////////
+
+// requiredProfileFields are the fields an asset profile must have to be saved
+var requiredProfileFields = []string{"sector", "country"}
+
+// optionalProfileFields are the fields reported when missing but not required
+var optionalProfileFields = []string{"industry", "fullTimeEmployees", "website", "phone", "street", "city", "province", "postalCode"}
+
+// profileField describes how to check and copy one asset profile field
+type profileField struct {
+	name  string
+	found func(a *entities.AssetProfile) bool
+	copy  func(dst, src *entities.AssetProfile)
+}
+
+// profileFields lists every extractable asset profile field
+var profileFields = []profileField{
+	{
+		name:  "sector",
+		found: func(a *entities.AssetProfile) bool { return a.Sector != "" },
+		copy:  func(dst, src *entities.AssetProfile) { dst.Sector = src.Sector },
+	},
+	{
+		name:  "country",
+		found: func(a *entities.AssetProfile) bool { return a.Country != "" },
+		copy:  func(dst, src *entities.AssetProfile) { dst.Country = src.Country },
+	},
+	{
+		name:  "industry",
+		found: func(a *entities.AssetProfile) bool { return a.Industry != "" },
+		copy:  func(dst, src *entities.AssetProfile) { dst.Industry = src.Industry },
+	},
+	{
+		name:  "fullTimeEmployees",
+		found: func(a *entities.AssetProfile) bool { return a.FullTimeEmployees > 0 },
+		copy:  func(dst, src *entities.AssetProfile) { dst.FullTimeEmployees = src.FullTimeEmployees },
+	},
+	{
+		name:  "website",
+		found: func(a *entities.AssetProfile) bool { return a.Website != "" },
+		copy:  func(dst, src *entities.AssetProfile) { dst.Website = src.Website },
+	},
+	{
+		name:  "phone",
+		found: func(a *entities.AssetProfile) bool { return a.Phone != "" },
+		copy:  func(dst, src *entities.AssetProfile) { dst.Phone = src.Phone },
+	},
+	{
+		name:  "street",
+		found: func(a *entities.AssetProfile) bool { return a.Street != "" },
+		copy:  func(dst, src *entities.AssetProfile) { dst.Street = src.Street },
+	},
+	{
+		name:  "city",
+		found: func(a *entities.AssetProfile) bool { return a.City != "" },
+		copy:  func(dst, src *entities.AssetProfile) { dst.City = src.City },
+	},
+	{
+		name:  "province",
+		found: func(a *entities.AssetProfile) bool { return a.Province != "" },
+		copy:  func(dst, src *entities.AssetProfile) { dst.Province = src.Province },
+	},
+	{
+		name:  "postalCode",
+		found: func(a *entities.AssetProfile) bool { return a.PostalCode != "" },
+		copy:  func(dst, src *entities.AssetProfile) { dst.PostalCode = src.PostalCode },
+	},
+}
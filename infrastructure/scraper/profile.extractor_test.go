@@ -0,0 +1,50 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+// appleStateWithoutIndustryAndPhone is the root.App.main state of apple missing the industry and phone
+const appleStateWithoutIndustryAndPhone = `<script>(function (root) {
+root.App || (root.App = {});
+root.App.main = {"context":{"dispatcher":{"stores":{"QuoteSummaryStore":{"assetProfile":{"address1":"One Apple Park Way","city":"Cupertino","state":"CA","zip":"95014","country":"United States","website":"http:\/\/www.apple.com","sector":"Technology","fullTimeEmployees":{"raw":147000,"fmt":"147,000"}}}}}}};
+}(this));
+</script>`
+
+func TestExtractorChainFillsMissingFields(t *testing.T) {
+	body := appleProfilePage + appleStateWithoutIndustryAndPhone
+
+	extraction, err := ExtractFromHTML(DefaultProfileExtractor(), []byte(body), apple.Ticker)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+
+	if !reflect.DeepEqual(*extraction.AssetProfile, apple) {
+		t.Errorf("asset profile = %+v, want %+v", *extraction.AssetProfile, apple)
+	}
+
+	want := map[string]string{
+		"sector":            "state",
+		"country":           "state",
+		"industry":          "html",
+		"fullTimeEmployees": "state",
+		"website":           "state",
+		"phone":             "html",
+		"street":            "state",
+		"city":              "state",
+		"province":          "state",
+		"postalCode":        "state",
+	}
+	if !reflect.DeepEqual(extraction.Sources, want) {
+		t.Errorf("sources = %v, want %v", extraction.Sources, want)
+	}
+}
+
+func TestExtractorChainProfileNotFound(t *testing.T) {
+	body := `<html><body><form class="consent-form"></form></body></html>`
+
+	if _, err := ExtractFromHTML(DefaultProfileExtractor(), []byte(body), "X"); err != ErrProfileNotFound {
+		t.Errorf("error = %v, want %v", err, ErrProfileNotFound)
+	}
+}
@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// scrapeResult is the outcome of scraping the profile of a ticker
+type scrapeResult struct {
+	ticker    string
+	source    string
+	failure   *entities.TickerFailure
+	unchanged bool
+	notFound  bool
+}
+
+// succeeded reports whether the profile was scraped and saved
+func (r scrapeResult) succeeded() bool {
+	return r.failure == nil
+}
+
+// resultCollector receives the results sent by the collector callbacks on a channel
+// and handles them one at a time, so the callbacks running in parallel never share state
+type resultCollector struct {
+	results chan scrapeResult
+	done    chan struct{}
+}
+
+// newResultCollector starts handling the results until the collector is closed
+func newResultCollector(handle func(scrapeResult)) *resultCollector {
+	c := &resultCollector{
+		results: make(chan scrapeResult),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(c.done)
+
+		for result := range c.results {
+			handle(result)
+		}
+	}()
+
+	return c
+}
+
+// send hands a result over to the collector
+func (c *resultCollector) send(result scrapeResult) {
+	c.results <- result
+}
+
+// close stops accepting results and waits until all of them are handled
+func (c *resultCollector) close() {
+	close(c.results)
+	<-c.done
+}
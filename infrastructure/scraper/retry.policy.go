@@ -0,0 +1,121 @@
+package scraper
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// RetryPolicy configures how failed profile requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per ticker, including the first one
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on every retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts
+	MaxDelay time.Duration
+	// RetryableStatusCodes are the http status codes worth retrying
+	RetryableStatusCodes []int
+	// RetryNetworkErrors retries requests that failed without a response,
+	// such as timeouts and connection resets
+	RetryNetworkErrors bool
+}
+
+// DefaultRetryPolicy retries rate limits, server errors and network errors up to three attempts
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    30 * time.Second,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryNetworkErrors: true,
+	}
+}
+
+// NoRetryPolicy never retries
+func NoRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 1,
+	}
+}
+
+// ShouldRetry reports whether a request that failed on the given attempt is retried
+func (p RetryPolicy) ShouldRetry(attempt int, statusCode int, err error) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+
+	if statusCode == 0 {
+		_, isNetErr := err.(net.Error)
+		return p.RetryNetworkErrors && isNetErr
+	}
+
+	for _, code := range p.RetryableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Backoff returns how long to wait before retrying a request that failed on the given attempt.
+// It grows exponentially with equal jitter, and honours the Retry-After header of the response
+func (p RetryPolicy) Backoff(attempt int, r *colly.Response) time.Duration {
+	if retryAfter := retryAfterDelay(r); retryAfter > 0 {
+		return p.capDelay(retryAfter)
+	}
+
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = p.capDelay(delay)
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// capDelay caps the delay to the max delay when there is one
+func (p RetryPolicy) capDelay(delay time.Duration) time.Duration {
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// retryAfterDelay parses the Retry-After header in seconds
+func retryAfterDelay(r *colly.Response) time.Duration {
+	if r == nil || r.Headers == nil {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(r.Headers.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// requestAttempt returns the attempt number of a request, starting at 1
+func requestAttempt(ctx *colly.Context) int {
+	if attempt, ok := ctx.GetAny("attempt").(int); ok {
+		return attempt
+	}
+	return 1
+}
@@ -0,0 +1,57 @@
+package scraper
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	tests := []struct {
+		attempt    int
+		statusCode int
+		err        error
+		want       bool
+	}{
+		{attempt: 1, statusCode: http.StatusTooManyRequests, want: true},
+		{attempt: 2, statusCode: http.StatusServiceUnavailable, want: true},
+		{attempt: 3, statusCode: http.StatusServiceUnavailable, want: false},
+		{attempt: 1, statusCode: http.StatusNotFound, want: false},
+		{attempt: 1, err: errors.New("parse error"), want: false},
+	}
+
+	for _, test := range tests {
+		if got := policy.ShouldRetry(test.attempt, test.statusCode, test.err); got != test.want {
+			t.Errorf("ShouldRetry(%d, %d, %v) = %v, want %v", test.attempt, test.statusCode, test.err, got, test.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+
+	bounds := map[int][2]time.Duration{
+		1: {500 * time.Millisecond, time.Second},
+		2: {time.Second, 2 * time.Second},
+		3: {2 * time.Second, 4 * time.Second},
+		4: {2 * time.Second, 4 * time.Second},
+	}
+
+	for attempt, bound := range bounds {
+		for i := 0; i < 20; i++ {
+			if d := policy.Backoff(attempt, nil); d < bound[0] || d > bound[1] {
+				t.Errorf("Backoff(%d) = %v, want between %v and %v", attempt, d, bound[0], bound[1])
+			}
+		}
+	}
+
+	headers := http.Header{"Retry-After": []string{"3"}}
+	if d := policy.Backoff(1, &colly.Response{Headers: &headers}); d != 3*time.Second {
+		t.Errorf("Backoff with Retry-After = %v, want 3s", d)
+	}
+}
@@ -0,0 +1,193 @@
+package scraper
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// runRecorder collects the outcome of every ticker of a scrape run into a run report
+type runRecorder struct {
+	mu        sync.Mutex
+	report    *entities.RunReport
+	startedAt time.Time
+	latencies []time.Duration
+}
+
+// newRunRecorder starts recording a new scrape run of a source
+func newRunRecorder(source string) *runRecorder {
+	startedAt := time.Now().UTC()
+
+	return &runRecorder{
+		report: &entities.RunReport{
+			RunID:     uuid.New().String(),
+			Source:    source,
+			StartedAt: startedAt.Unix(),
+		},
+		startedAt: startedAt,
+	}
+}
+
+// runID returns the id of the run
+func (r *runRecorder) runID() string {
+	return r.report.RunID
+}
+
+// notAttemptedCount returns the number of tickers left out of the run so far
+func (r *runRecorder) notAttemptedCount() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.report.NotAttempted
+}
+
+// checkpoint records the checkpoint page scraped by the run
+func (r *runRecorder) checkpoint(checkpoint *entities.Checkpoint) {
+	if checkpoint == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.PageIndex = checkpoint.PageIndex
+	r.report.PageSize = checkpoint.PageSize
+}
+
+// requested records the number of tickers to scrape
+func (r *runRecorder) requested(count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Requested += int64(count)
+}
+
+// skipped records a ticker that could not even be requested
+func (r *runRecorder) skipped() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Skipped++
+}
+
+// succeeded records a scraped ticker
+func (r *runRecorder) succeeded(ticker string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Succeeded++
+	r.report.ScrapedTickers = append(r.report.ScrapedTickers, ticker)
+}
+
+// unchanged records a scraped ticker whose stored profile was already up to date
+func (r *runRecorder) unchanged() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Unchanged++
+}
+
+// failed records a ticker that could not be scraped and why
+func (r *runRecorder) failed(failure *entities.TickerFailure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Failed++
+	r.report.Failures = append(r.report.Failures, failure)
+}
+
+// notAttempted records the tickers left out to finish before the deadline
+func (r *runRecorder) notAttempted(tickers []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.NotAttempted += int64(len(tickers))
+	r.report.NotAttemptedTickers = append(r.report.NotAttemptedTickers, tickers...)
+}
+
+// unmapped records the Yahoo sector and industry values the classifier has no mapping for,
+// once per run. Empty values are not recorded
+func (r *runRecorder) unmapped(sector string, industry string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.UnmappedSectors = appendDistinct(r.report.UnmappedSectors, sector)
+	r.report.UnmappedIndustries = appendDistinct(r.report.UnmappedIndustries, industry)
+}
+
+// appendDistinct appends value to values unless it is empty or already there
+func appendDistinct(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
+
+// observeLatency records how long an http request took
+func (r *runRecorder) observeLatency(latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies = append(r.latencies, latency)
+}
+
+// finish stamps the duration and the latency percentiles and returns the report
+func (r *runRecorder) finish() *entities.RunReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	finishedAt := time.Now().UTC()
+
+	latencies := append([]time.Duration{}, r.latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	sort.Strings(r.report.UnmappedSectors)
+	sort.Strings(r.report.UnmappedIndustries)
+
+	r.report.FinishedAt = finishedAt.Unix()
+	r.report.DurationMs = finishedAt.Sub(r.startedAt).Milliseconds()
+	r.report.LatencyP50Ms = percentile(latencies, 50).Milliseconds()
+	r.report.LatencyP95Ms = percentile(latencies, 95).Milliseconds()
+
+	return r.report
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// latencyTransport times every request going through the next transport
+type latencyTransport struct {
+	next    http.RoundTripper
+	observe func(time.Duration)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *latencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.observe(time.Since(start))
+
+	return resp, err
+}
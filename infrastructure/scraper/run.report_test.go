@@ -0,0 +1,31 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 20; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		latencies []time.Duration
+		p         int
+		want      time.Duration
+	}{
+		{latencies: nil, p: 50, want: 0},
+		{latencies: latencies[:1], p: 95, want: time.Millisecond},
+		{latencies: latencies, p: 50, want: 10 * time.Millisecond},
+		{latencies: latencies, p: 95, want: 19 * time.Millisecond},
+		{latencies: latencies, p: 100, want: 20 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		if got := percentile(test.latencies, test.p); got != test.want {
+			t.Errorf("percentile(%d of %d) = %v, want %v", test.p, len(test.latencies), got, test.want)
+		}
+	}
+}
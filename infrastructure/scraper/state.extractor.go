@@ -0,0 +1,163 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// ErrStateNotFound is returned when a page has no embedded asset profile state
+var ErrStateNotFound = errors.New("asset profile state not found")
+
+// appMainPattern matches the application state Yahoo assigns to root.App.main
+var appMainPattern = regexp.MustCompile(`(?s)root\.App\.main\s*=\s*(\{.*\})\s*;\s*\}\(this\)\);`)
+
+// appMainState is the part of root.App.main holding the quote summary store
+type appMainState struct {
+	Context struct {
+		Dispatcher struct {
+			Stores struct {
+				QuoteSummaryStore struct {
+					AssetProfile *assetProfileState `json:"assetProfile"`
+				} `json:"QuoteSummaryStore"`
+			} `json:"stores"`
+		} `json:"dispatcher"`
+	} `json:"context"`
+}
+
+// quoteSummaryState is the quoteSummary API response embedded in newer pages
+type quoteSummaryState struct {
+	QuoteSummary struct {
+		Result []struct {
+			AssetProfile *assetProfileState `json:"assetProfile"`
+		} `json:"result"`
+	} `json:"quoteSummary"`
+}
+
+// fetchedState wraps a prefetched API response whose body is a JSON string
+type fetchedState struct {
+	Body string `json:"body"`
+}
+
+// assetProfileState is Yahoo's assetProfile module
+type assetProfileState struct {
+	Address1          string   `json:"address1"`
+	Address2          string   `json:"address2"`
+	Address3          string   `json:"address3"`
+	City              string   `json:"city"`
+	State             string   `json:"state"`
+	Zip               string   `json:"zip"`
+	Country           string   `json:"country"`
+	Phone             string   `json:"phone"`
+	Website           string   `json:"website"`
+	Industry          string   `json:"industry"`
+	Sector            string   `json:"sector"`
+	FullTimeEmployees stateInt `json:"fullTimeEmployees"`
+}
+
+// stateInt decodes a number that Yahoo emits either plain or as {"raw": n, "fmt": "n"}
+type stateInt int64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (i *stateInt) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Raw int64 `json:"raw"`
+	}
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*i = stateInt(raw.Raw)
+		return nil
+	}
+
+	var n float64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+
+	*i = stateInt(n)
+	return nil
+}
+
+// StateExtractor extracts the asset profile from the JSON application state
+// (root.App.main or the quoteSummary response) embedded in the page
+type StateExtractor struct{}
+
+// Name implements ProfileExtractor
+func (x *StateExtractor) Name() string {
+	return "state"
+}
+
+// Extract implements ProfileExtractor
+func (x *StateExtractor) Extract(doc *goquery.Document, ticker string) (*Extraction, error) {
+	var state *assetProfileState
+
+	doc.Find("script").EachWithBreak(func(_ int, script *goquery.Selection) bool {
+		state = findAssetProfileState(script.Text())
+		return state == nil
+	})
+
+	if state == nil {
+		return nil, ErrStateNotFound
+	}
+
+	return newExtraction(x.Name(), state.toEntity(ticker)), nil
+}
+
+// findAssetProfileState looks for the assetProfile module in a script body
+func findAssetProfileState(script string) *assetProfileState {
+	if matches := appMainPattern.FindStringSubmatch(script); len(matches) == 2 {
+		var appMain appMainState
+		if err := json.Unmarshal([]byte(matches[1]), &appMain); err == nil {
+			return appMain.Context.Dispatcher.Stores.QuoteSummaryStore.AssetProfile
+		}
+	}
+
+	if !strings.Contains(script, "quoteSummary") {
+		return nil
+	}
+
+	var fetched fetchedState
+	if err := json.Unmarshal([]byte(script), &fetched); err == nil && fetched.Body != "" {
+		script = fetched.Body
+	}
+
+	var summary quoteSummaryState
+	if err := json.Unmarshal([]byte(script), &summary); err != nil {
+		return nil
+	}
+
+	for _, result := range summary.QuoteSummary.Result {
+		if result.AssetProfile != nil {
+			return result.AssetProfile
+		}
+	}
+
+	return nil
+}
+
+// toEntity converts the assetProfile module to an asset profile entity
+func (a *assetProfileState) toEntity(ticker string) *entities.AssetProfile {
+	var street []string
+	for _, line := range []string{a.Address1, a.Address2, a.Address3} {
+		if line = strings.TrimSpace(line); line != "" {
+			street = append(street, line)
+		}
+	}
+
+	return &entities.AssetProfile{
+		Ticker:            ticker,
+		Sector:            strings.TrimSpace(a.Sector),
+		Industry:          strings.TrimSpace(a.Industry),
+		FullTimeEmployees: int64(a.FullTimeEmployees),
+		Website:           strings.TrimSpace(a.Website),
+		Phone:             strings.TrimSpace(a.Phone),
+		Street:            strings.Join(street, ", "),
+		City:              strings.TrimSpace(a.City),
+		Province:          strings.TrimSpace(a.State),
+		PostalCode:        strings.TrimSpace(a.Zip),
+		Country:           strings.TrimSpace(a.Country),
+	}
+}
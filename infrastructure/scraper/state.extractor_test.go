@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// royalBankStatePage is a profile page holding the asset profile only in the root.App.main state
+const royalBankStatePage = `<!DOCTYPE html>
+<html lang="en-CA">
+<head><title>Royal Bank of Canada (RY.TO) Company Profile &amp; Facts - Yahoo Finance</title></head>
+<body>
+<div id="app"><div id="render-target-default"></div></div>
+<script>(function (root) {
+/* -- Data -- */
+root.App || (root.App = {});
+root.App.main = {"context":{"dispatcher":{"stores":{"PageStore":{"pageData":{"pageName":"quote/profile"}},"QuoteSummaryStore":{"symbol":"RY.TO","assetProfile":{"address1":"1 Place Ville Marie","address2":"Royal Bank Plaza","city":"Montreal","state":"QC","zip":"H3B 4R8","country":"Canada","phone":"514-874-2110","website":"http:\/\/www.rbc.com","industry":"Banks—Diversified","sector":"Financial Services","fullTimeEmployees":{"raw":85301,"fmt":"85,301","longFmt":"85,301"},"maxAge":86400}}}}},"plugins":{}};
+}(this));
+</script>
+</body>
+</html>`
+
+// royalBankQuoteSummaryPage is a profile page holding the asset profile in a prefetched quoteSummary response
+const royalBankQuoteSummaryPage = `<html><body>
+<script type="application/json" data-sveltekit-fetched>{"status":200,"body":"{\"quoteSummary\":{\"result\":[{\"assetProfile\":{\"address1\":\"1 Place Ville Marie\",\"address2\":\"Royal Bank Plaza\",\"city\":\"Montreal\",\"state\":\"QC\",\"zip\":\"H3B 4R8\",\"country\":\"Canada\",\"phone\":\"514-874-2110\",\"website\":\"http://www.rbc.com\",\"industry\":\"Banks—Diversified\",\"sector\":\"Financial Services\",\"fullTimeEmployees\":85301}}],\"error\":null}}"}</script>
+</body></html>`
+
+var royalBank = entities.AssetProfile{
+	Ticker:            "RY",
+	Sector:            "Financial Services",
+	Industry:          "Banks—Diversified",
+	FullTimeEmployees: 85301,
+	Website:           "http://www.rbc.com",
+	Phone:             "514-874-2110",
+	Street:            "1 Place Ville Marie, Royal Bank Plaza",
+	City:              "Montreal",
+	Province:          "QC",
+	PostalCode:        "H3B 4R8",
+	Country:           "Canada",
+}
+
+func TestStateExtractor(t *testing.T) {
+	for name, page := range map[string]string{
+		"root.App.main": royalBankStatePage,
+		"quoteSummary":  royalBankQuoteSummaryPage,
+	} {
+		extraction, err := ExtractFromHTML(&StateExtractor{}, []byte(page), royalBank.Ticker)
+		if err != nil {
+			t.Errorf("%s: extract: %v", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(*extraction.AssetProfile, royalBank) {
+			t.Errorf("%s: asset profile = %+v, want %+v", name, *extraction.AssetProfile, royalBank)
+		}
+	}
+
+	if _, err := ExtractFromHTML(&StateExtractor{}, []byte(appleProfilePage), apple.Ticker); err != ErrStateNotFound {
+		t.Errorf("extract html layout error = %v, want %v", err, ErrStateNotFound)
+	}
+}
@@ -0,0 +1,54 @@
+package scraper
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/symbol"
+)
+
+// regionsOf returns the regions whose sites are tried in order for a ticker of a source,
+// leaving out the regions without a site
+func regionsOf(sites *entities.YahooSites, source string, ticker string) []string {
+	regions, ok := sites.RegionsByExchange[symbol.Exchange(ticker)]
+	if !ok {
+		regions, ok = sites.RegionsBySource[strings.ToUpper(source)]
+	}
+	if !ok {
+		regions = sites.Regions
+	}
+
+	var known []string
+	for _, region := range regions {
+		if _, ok := sites.URLTemplates[region]; ok {
+			known = append(known, region)
+		}
+	}
+
+	return known
+}
+
+// profileURL returns the url of the profile page of a Yahoo symbol on the site of a region
+func profileURL(sites *entities.YahooSites, region string, yahooSymbol string) string {
+	return strings.ReplaceAll(sites.URLTemplates[region], "{symbol}", yahooSymbol)
+}
+
+// allowedDomains returns the hosts of the sites
+func allowedDomains(sites *entities.YahooSites) []string {
+	hosts := make(map[string]bool)
+	for _, urlTemplate := range sites.URLTemplates {
+		if u, err := url.Parse(urlTemplate); err == nil && u.Host != "" {
+			hosts[u.Host] = true
+		}
+	}
+
+	var domains []string
+	for host := range hosts {
+		domains = append(domains, host)
+	}
+	sort.Strings(domains)
+
+	return domains
+}
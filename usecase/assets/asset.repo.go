@@ -15,6 +15,8 @@ type Reader interface {
 	CountAssetsBySource(context.Context, string) (int64, error)
 	FindAllAssetsBySource(context.Context, string) ([]*entities.Asset, error)
 	FindAssetsBySourceFromCheckpoint(context.Context, string, *entities.Checkpoint) ([]*entities.Asset, error)
+	FindStaleAssetsBySource(context.Context, string, *entities.RefreshPolicy, int64) ([]*entities.Asset, error)
+	FindAssetIDBySourceAt(context.Context, string, int64) (string, error)
 }
 
 // Writer interface
@@ -2,8 +2,12 @@ package assets
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
 	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/checkpoint"
 )
@@ -30,23 +34,147 @@ func (s *Service) GetAssetsBySource(ctx context.Context, source string) ([]*enti
 	return s.assetRepo.FindAllAssetsBySource(ctx, source)
 }
 
-// GetAssetsBySourceFromCheckpoint gets all assets from checkpoint
-func (s *Service) GetAssetsBySourceFromCheckpoint(ctx context.Context, source string, pageSize int64) ([]*entities.Asset, error) {
-	s.log.Info(ctx, "getting assets from checkpoint")
+// PartitionAssetsBySource splits the assets of a source, newest first, into shards of shardSize assets
+// for a job. The shards are bounded by the ids of their last asset, so assets added later don't shift them
+func (s *Service) PartitionAssetsBySource(ctx context.Context, jobID string, source string, shardSize int64) ([]*entities.Shard, error) {
 	numAssets, err := s.assetRepo.CountAssetsBySource(ctx, source)
 	if err != nil {
 		s.log.Error(ctx, "count assets failed", "error", err)
+		return nil, err
+	}
+
+	var shards []*entities.Shard
+
+	after := ""
+	for offset := int64(0); offset < numAssets; offset += shardSize {
+		// the last shard takes the rest of the assets
+		last := ""
+		if offset+shardSize < numAssets {
+			last, err = s.assetRepo.FindAssetIDBySourceAt(ctx, source, offset+shardSize-1)
+			if err != nil {
+				s.log.Error(ctx, "find shard boundary failed", "error", err, "offset", offset+shardSize-1)
+				return nil, err
+			}
+		}
+
+		shards = append(shards, &entities.Shard{
+			JobID:  jobID,
+			Source: strings.ToUpper(source),
+			Index:  int64(len(shards)),
+			Size:   shardSize,
+			After:  after,
+			Last:   last,
+			Status: consts.SHARD_PENDING,
+		})
+
+		// assets were removed since they were counted
+		if last == "" {
+			break
+		}
+		after = last
+	}
+
+	for _, shard := range shards {
+		shard.Total = int64(len(shards))
+	}
+
+	return shards, nil
+}
+
+// GetAssetsBySourceFromShard gets the assets of a shard
+func (s *Service) GetAssetsBySourceFromShard(ctx context.Context, shard *entities.Shard) ([]*entities.Asset, error) {
+	s.log.Info(ctx, "getting assets from shard", "jobId", shard.JobID, "index", shard.Index)
+	return s.assetRepo.FindAssetsBySourceFromCheckpoint(ctx, shard.Source, &entities.Checkpoint{
+		After: shard.After,
+		Next:  shard.Last,
+	})
+}
+
+// GetStaleAssetsBySource finds up to limit assets of a source most due for a scrape by the refresh policy,
+// all of them when limit is below 1
+func (s *Service) GetStaleAssetsBySource(ctx context.Context, source string, policy *entities.RefreshPolicy, limit int64) ([]*entities.Asset, error) {
+	s.log.Info(ctx, "finding stale assets by source", "source", source, "limit", limit)
+	if policy == nil {
+		policy = &entities.RefreshPolicy{}
+	}
+	return s.assetRepo.FindStaleAssetsBySource(ctx, source, policy, limit)
+}
+
+// GetAssetsBySourceFromCheckpoint claims a checkpoint page for a run and gets its assets. Concurrent
+// runs get distinct pages. The run commits or abandons the checkpoint once it is done with the page
+func (s *Service) GetAssetsBySourceFromCheckpoint(ctx context.Context, runID string, source string, pageSize int64) ([]*entities.Asset, *entities.Checkpoint, error) {
+	s.log.Info(ctx, "getting assets from checkpoint")
+	checkpoint, err := s.claimCheckpoint(ctx, source, runID, pageSize)
+	if err != nil {
+		s.log.Error(ctx, "claim checkpoint failed", "error", err)
+		return nil, nil, err
+	}
+
+	// a reclaimed page is bounded by its last asset
+	if checkpoint.Reclaimed {
+		assets, err := s.assetRepo.FindAssetsBySourceFromCheckpoint(ctx, source, checkpoint)
+		if err != nil {
+			s.log.Error(ctx, "find assets from checkpoint failed", "error", err)
+			s.AbandonCheckpoint(ctx, source, runID)
+			return nil, nil, err
+		}
+
+		return assets, checkpoint, nil
 	}
 
-	checkpoint, err := s.checkpointService.UpdateCheckpoint(ctx, pageSize, numAssets)
+	// find one asset past the page to know whether there is a page after it
+	page := *checkpoint
+	page.PageSize++
+
+	assets, err := s.assetRepo.FindAssetsBySourceFromCheckpoint(ctx, source, &page)
 	if err != nil {
-		s.log.Error(ctx, "find and update checkpoint failed", "error", err)
+		s.log.Error(ctx, "find assets from checkpoint failed", "error", err)
+		s.AbandonCheckpoint(ctx, source, runID)
+		return nil, nil, err
+	}
+
+	// the next page follows the last asset of this one, or wraps around to the first page
+	checkpoint.Next = ""
+	if int64(len(assets)) > checkpoint.PageSize {
+		assets = assets[:checkpoint.PageSize]
+		checkpoint.Next = assets[len(assets)-1].ID
 	}
 
-	if checkpoint == nil {
-		s.log.Error(ctx, "checkpoint is nil", "checkpoint", checkpoint)
-		return nil, nil
+	if err := s.checkpointService.ReserveCheckpoint(ctx, checkpoint); err != nil {
+		s.log.Error(ctx, "reserve checkpoint failed", "error", err)
+		s.AbandonCheckpoint(ctx, source, runID)
+		return nil, nil, err
 	}
 
-	return s.assetRepo.FindAssetsBySourceFromCheckpoint(ctx, source, checkpoint)
+	return assets, checkpoint, nil
+}
+
+// claimCheckpoint claims a checkpoint page, waiting a little while other runs lock the next page
+func (s *Service) claimCheckpoint(ctx context.Context, source string, runID string, pageSize int64) (*entities.Checkpoint, error) {
+	for attempt := 1; ; attempt++ {
+		cp, err := s.checkpointService.ClaimCheckpoint(ctx, source, runID, pageSize)
+		if !errors.Is(err, checkpoint.ErrCheckpointInProgress) || attempt >= consts.CHECKPOINT_CLAIM_ATTEMPTS {
+			return cp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(attempt*consts.CHECKPOINT_CLAIM_BACKOFF_MS) * time.Millisecond):
+		}
+	}
+}
+
+// CommitCheckpoint marks the checkpoint page of the run as done
+func (s *Service) CommitCheckpoint(ctx context.Context, checkpoint *entities.Checkpoint) error {
+	return s.checkpointService.CommitCheckpoint(ctx, checkpoint.Source, checkpoint.RunID)
+}
+
+// AbandonCheckpoint releases the page claimed by the run without moving past it
+func (s *Service) AbandonCheckpoint(ctx context.Context, source string, runID string) error {
+	if err := s.checkpointService.AbandonCheckpoint(ctx, source, runID); err != nil {
+		s.log.Error(ctx, "abandon checkpoint failed", "error", err, "runId", runID)
+		return err
+	}
+	return nil
 }
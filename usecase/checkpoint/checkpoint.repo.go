@@ -2,10 +2,23 @@ package checkpoint
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
 )
 
+// ErrCheckpointInProgress is returned when another run locks the next page of the checkpoint
+// and no leased page is up for reclaim
+var ErrCheckpointInProgress = errors.New("checkpoint is claimed by another run")
+
+// ErrGlobalCheckpointNotMigrated is returned instead of creating the checkpoint the global checkpoint,
+// used before checkpoints were keyed by source, is migrated to while it is still there
+var ErrGlobalCheckpointNotMigrated = errors.New("global checkpoint is not migrated, run -migrate-checkpoints first")
+
+// ErrCheckpointNotClaimed is returned when reserving, committing or abandoning a page the run does not hold
+var ErrCheckpointNotClaimed = errors.New("checkpoint is not claimed by the run")
+
 ///////////////////////////////////////////////////////////
 // Asset Price Repository Interface
 ///////////////////////////////////////////////////////////
@@ -16,7 +29,10 @@ type Reader interface {
 
 // Writer interface
 type Writer interface {
-	UpdateCheckpoint(ctx context.Context, pageSize int64, numAssets int64) (*entities.Checkpoint, error)
+	ClaimCheckpoint(ctx context.Context, source string, jobType string, runID string, pageSize int64, lease time.Duration) (*entities.Checkpoint, error)
+	ReserveCheckpoint(ctx context.Context, source string, jobType string, page *entities.Checkpoint, lease time.Duration) error
+	CommitCheckpoint(ctx context.Context, source string, jobType string, runID string) error
+	AbandonCheckpoint(ctx context.Context, source string, jobType string, runID string) error
 }
 
 // Repo interface
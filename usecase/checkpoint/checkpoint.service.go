@@ -2,8 +2,10 @@ package checkpoint
 
 import (
 	"context"
+	"time"
 
 	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
 	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
 )
 
@@ -21,8 +23,28 @@ func NewService(checkpointRepo Repo, log logger.ContextLog) *Service {
 	}
 }
 
-// GetAllAssets gets all assets
-func (s *Service) UpdateCheckpoint(ctx context.Context, pageSize int64, numAssets int64) (*entities.Checkpoint, error) {
-	s.log.Info(ctx, "updating checkpoint")
-	return s.checkpointRepo.UpdateCheckpoint(ctx, pageSize, numAssets)
+// ClaimCheckpoint claims a page of the asset profile checkpoint of a source for a run: a reclaimed
+// page leased by a run that did not complete it, or the next page, which the run reserves
+func (s *Service) ClaimCheckpoint(ctx context.Context, source string, runID string, pageSize int64) (*entities.Checkpoint, error) {
+	s.log.Info(ctx, "claiming checkpoint", "source", source, "runId", runID)
+	return s.checkpointRepo.ClaimCheckpoint(ctx, source, consts.ASSET_PROFILE_JOB, runID, pageSize, consts.CHECKPOINT_LEASE_SECONDS*time.Second)
+}
+
+// ReserveCheckpoint leases the next page claimed by a run once its last asset is known,
+// so the other runs claim the page after it
+func (s *Service) ReserveCheckpoint(ctx context.Context, page *entities.Checkpoint) error {
+	s.log.Info(ctx, "reserving checkpoint", "source", page.Source, "runId", page.RunID, "index", page.PageIndex, "next", page.Next)
+	return s.checkpointRepo.ReserveCheckpoint(ctx, page.Source, consts.ASSET_PROFILE_JOB, page, consts.CHECKPOINT_LEASE_SECONDS*time.Second)
+}
+
+// CommitCheckpoint marks the page leased by a run as done
+func (s *Service) CommitCheckpoint(ctx context.Context, source string, runID string) error {
+	s.log.Info(ctx, "committing checkpoint", "source", source, "runId", runID)
+	return s.checkpointRepo.CommitCheckpoint(ctx, source, consts.ASSET_PROFILE_JOB, runID)
+}
+
+// AbandonCheckpoint releases the page claimed by a run so the next claim gets it again
+func (s *Service) AbandonCheckpoint(ctx context.Context, source string, runID string) error {
+	s.log.Info(ctx, "abandoning checkpoint", "source", source, "runId", runID)
+	return s.checkpointRepo.AbandonCheckpoint(ctx, source, consts.ASSET_PROFILE_JOB, runID)
 }
@@ -0,0 +1,28 @@
+package failure
+
+import (
+	"context"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+///////////////////////////////////////////////////////////
+// Failed Ticker Repository Interface
+///////////////////////////////////////////////////////////
+
+// Reader interface
+type Reader interface {
+	FindFailedTickersBySource(ctx context.Context, source string, limit int64) ([]*entities.FailedTicker, error)
+}
+
+// Writer interface
+type Writer interface {
+	UpsertFailedTicker(ctx context.Context, failedTicker *entities.FailedTicker, quarantineAfter int64) (*entities.FailedTicker, error)
+	DeleteFailedTicker(ctx context.Context, ticker string, source string) error
+}
+
+// Repo interface
+type Repo interface {
+	Reader
+	Writer
+}
@@ -0,0 +1,51 @@
+package failure
+
+import (
+	"context"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// Service exposure
+type Service struct {
+	repo            Repo
+	quarantineAfter int64
+	log             logger.ContextLog
+}
+
+// NewService create new service. Tickers failing quarantineAfter times in a row are quarantined
+func NewService(r Repo, quarantineAfter int64, l logger.ContextLog) *Service {
+	return &Service{
+		repo:            r,
+		quarantineAfter: quarantineAfter,
+		log:             l,
+	}
+}
+
+// RecordFailure records a failed scrape of a ticker
+func (s *Service) RecordFailure(ctx context.Context, failedTicker *entities.FailedTicker) error {
+	s.log.Info(ctx, "recording failed ticker", "ticker", failedTicker.Ticker, "reason", failedTicker.Reason)
+
+	f, err := s.repo.UpsertFailedTicker(ctx, failedTicker, s.quarantineAfter)
+	if err != nil {
+		return err
+	}
+
+	if f.Quarantined {
+		s.log.Info(ctx, "ticker quarantined", "ticker", f.Ticker, "failureCount", f.FailureCount)
+	}
+
+	return nil
+}
+
+// ClearFailure removes a ticker from the failed tickers once it is scraped successfully
+func (s *Service) ClearFailure(ctx context.Context, ticker string, source string) error {
+	return s.repo.DeleteFailedTicker(ctx, ticker, source)
+}
+
+// GetFailedTickers gets the failed tickers of a source that are not quarantined, oldest failure first
+func (s *Service) GetFailedTickers(ctx context.Context, source string, limit int64) ([]*entities.FailedTicker, error) {
+	s.log.Info(ctx, "getting failed tickers", "source", source, "limit", limit)
+	return s.repo.FindFailedTickersBySource(ctx, source, limit)
+}
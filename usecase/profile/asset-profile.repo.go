@@ -16,7 +16,8 @@ type Reader interface {
 
 // Writer interface
 type Writer interface {
-	UpsertAssetProfile(ctx context.Context, assetProfile *entities.AssetProfile) error
+	UpsertAssetProfile(ctx context.Context, assetProfile *entities.AssetProfile) (bool, error)
+	MarkAssetProfileNotFound(ctx context.Context, ticker string, deleteAfter int64) (bool, error)
 }
 
 // Repo interface
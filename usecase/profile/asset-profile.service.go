@@ -9,20 +9,39 @@ import (
 
 // Service exposure
 type Service struct {
-	repo Repo
-	log  logger.ContextLog
+	repo        Repo
+	deleteAfter int64
+	log         logger.ContextLog
 }
 
-// NewService create new service
-func NewService(r Repo, l logger.ContextLog) *Service {
+// NewService create new service. Profiles not found deleteAfter times in a row are soft deleted
+func NewService(r Repo, deleteAfter int64, l logger.ContextLog) *Service {
 	return &Service{
-		repo: r,
-		log:  l,
+		repo:        r,
+		deleteAfter: deleteAfter,
+		log:         l,
 	}
 }
 
-// AddAssetProfile add asset profile
-func (s *Service) AddAssetProfile(ctx context.Context, assetProfile *entities.AssetProfile) error {
+// AddAssetProfile add asset profile, reporting whether the stored profile was unchanged
+func (s *Service) AddAssetProfile(ctx context.Context, assetProfile *entities.AssetProfile) (bool, error) {
 	s.log.Info(ctx, "adding asset profile", "ticker", assetProfile.Ticker)
 	return s.repo.UpsertAssetProfile(ctx, assetProfile)
 }
+
+// RecordNotFound records that Yahoo does not know the ticker anymore. The stored profile of
+// a ticker not found too many times in a row is soft deleted until it is scraped again
+func (s *Service) RecordNotFound(ctx context.Context, ticker string) error {
+	s.log.Info(ctx, "recording ticker not found", "ticker", ticker)
+
+	deleted, err := s.repo.MarkAssetProfileNotFound(ctx, ticker, s.deleteAfter)
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		s.log.Info(ctx, "asset profile deleted", "ticker", ticker)
+	}
+
+	return nil
+}
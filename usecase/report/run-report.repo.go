@@ -0,0 +1,26 @@
+package report
+
+import (
+	"context"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+///////////////////////////////////////////////////////////
+// Run Report Repository Interface
+///////////////////////////////////////////////////////////
+
+// Reader interface
+type Reader interface {
+}
+
+// Writer interface
+type Writer interface {
+	InsertRunReport(ctx context.Context, report *entities.RunReport) error
+}
+
+// Repo interface
+type Repo interface {
+	Reader
+	Writer
+}
@@ -0,0 +1,28 @@
+package report
+
+import (
+	"context"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// Service exposure
+type Service struct {
+	repo Repo
+	log  logger.ContextLog
+}
+
+// NewService create new service
+func NewService(r Repo, l logger.ContextLog) *Service {
+	return &Service{
+		repo: r,
+		log:  l,
+	}
+}
+
+// AddRunReport saves the report of a scrape run
+func (s *Service) AddRunReport(ctx context.Context, report *entities.RunReport) error {
+	s.log.Info(ctx, "adding run report", "runId", report.RunID)
+	return s.repo.InsertRunReport(ctx, report)
+}
@@ -0,0 +1,26 @@
+package sector
+
+import (
+	"context"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+///////////////////////////////////////////////////////////
+// Sector Mapping Repository Interface
+///////////////////////////////////////////////////////////
+
+// Reader interface
+type Reader interface {
+	FindSectorMappings(ctx context.Context) ([]*entities.SectorMapping, error)
+}
+
+// Writer interface
+type Writer interface {
+}
+
+// Repo interface
+type Repo interface {
+	Reader
+	Writer
+}
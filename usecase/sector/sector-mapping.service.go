@@ -0,0 +1,398 @@
+package sector
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// gicsSectors are the GICS sectors by code
+var gicsSectors = map[string]string{
+	"10": "Energy",
+	"15": "Materials",
+	"20": "Industrials",
+	"25": "Consumer Discretionary",
+	"30": "Consumer Staples",
+	"35": "Health Care",
+	"40": "Financials",
+	"45": "Information Technology",
+	"50": "Communication Services",
+	"55": "Utilities",
+	"60": "Real Estate",
+}
+
+// gicsIndustryGroups are the GICS industry groups by code, the sector code followed by two digits
+var gicsIndustryGroups = map[string]string{
+	"1010": "Energy",
+	"1510": "Materials",
+	"2010": "Capital Goods",
+	"2020": "Commercial & Professional Services",
+	"2030": "Transportation",
+	"2510": "Automobiles & Components",
+	"2520": "Consumer Durables & Apparel",
+	"2530": "Consumer Services",
+	"2550": "Consumer Discretionary Distribution & Retail",
+	"3010": "Consumer Staples Distribution & Retail",
+	"3020": "Food, Beverage & Tobacco",
+	"3030": "Household & Personal Products",
+	"3510": "Health Care Equipment & Services",
+	"3520": "Pharmaceuticals, Biotechnology & Life Sciences",
+	"4010": "Banks",
+	"4020": "Financial Services",
+	"4030": "Insurance",
+	"4510": "Software & Services",
+	"4520": "Technology Hardware & Equipment",
+	"4530": "Semiconductors & Semiconductor Equipment",
+	"5010": "Telecommunication Services",
+	"5020": "Media & Entertainment",
+	"5510": "Utilities",
+	"6010": "Equity Real Estate Investment Trusts (REITs)",
+	"6020": "Real Estate Management & Development",
+}
+
+// gicsIndustries are the GICS industries by code, the industry group code followed by two digits
+var gicsIndustries = map[string]string{
+	"101010": "Energy Equipment & Services",
+	"101020": "Oil, Gas & Consumable Fuels",
+	"151010": "Chemicals",
+	"151020": "Construction Materials",
+	"151030": "Containers & Packaging",
+	"151040": "Metals & Mining",
+	"151050": "Paper & Forest Products",
+	"201010": "Aerospace & Defense",
+	"201020": "Building Products",
+	"201030": "Construction & Engineering",
+	"201040": "Electrical Equipment",
+	"201050": "Industrial Conglomerates",
+	"201060": "Machinery",
+	"201070": "Trading Companies & Distributors",
+	"202010": "Commercial Services & Supplies",
+	"202020": "Professional Services",
+	"203010": "Air Freight & Logistics",
+	"203020": "Passenger Airlines",
+	"203030": "Marine Transportation",
+	"203040": "Ground Transportation",
+	"203050": "Transportation Infrastructure",
+	"251010": "Automobile Components",
+	"251020": "Automobiles",
+	"252010": "Household Durables",
+	"252020": "Leisure Products",
+	"252030": "Textiles, Apparel & Luxury Goods",
+	"253010": "Hotels, Restaurants & Leisure",
+	"253020": "Diversified Consumer Services",
+	"255010": "Distributors",
+	"255030": "Broadline Retail",
+	"255040": "Specialty Retail",
+	"301010": "Consumer Staples Distribution & Retail",
+	"302010": "Beverages",
+	"302020": "Food Products",
+	"302030": "Tobacco",
+	"303010": "Household Products",
+	"303020": "Personal Care Products",
+	"351010": "Health Care Equipment & Supplies",
+	"351020": "Health Care Providers & Services",
+	"351030": "Health Care Technology",
+	"352010": "Biotechnology",
+	"352020": "Pharmaceuticals",
+	"352030": "Life Sciences Tools & Services",
+	"401010": "Banks",
+	"402010": "Financial Services",
+	"402020": "Consumer Finance",
+	"402030": "Capital Markets",
+	"402040": "Mortgage Real Estate Investment Trusts (REITs)",
+	"403010": "Insurance",
+	"451020": "IT Services",
+	"451030": "Software",
+	"452010": "Communications Equipment",
+	"452020": "Technology Hardware, Storage & Peripherals",
+	"452030": "Electronic Equipment, Instruments & Components",
+	"453010": "Semiconductors & Semiconductor Equipment",
+	"501010": "Diversified Telecommunication Services",
+	"501020": "Wireless Telecommunication Services",
+	"502010": "Media",
+	"502020": "Entertainment",
+	"502030": "Interactive Media & Services",
+	"551010": "Electric Utilities",
+	"551020": "Gas Utilities",
+	"551030": "Multi-Utilities",
+	"551040": "Water Utilities",
+	"551050": "Independent Power and Renewable Electricity Producers",
+	"601010": "Diversified REITs",
+	"601025": "Industrial REITs",
+	"601030": "Hotel & Resort REITs",
+	"601040": "Office REITs",
+	"601050": "Health Care REITs",
+	"601060": "Residential REITs",
+	"601070": "Retail REITs",
+	"601080": "Specialized REITs",
+	"602010": "Real Estate Management & Development",
+}
+
+// yahooSectors maps the Yahoo sectors to the GICS sector codes
+var yahooSectors = map[string]string{
+	"Energy":                 "10",
+	"Basic Materials":        "15",
+	"Industrials":            "20",
+	"Consumer Cyclical":      "25",
+	"Consumer Defensive":     "30",
+	"Healthcare":             "35",
+	"Financial Services":     "40",
+	"Technology":             "45",
+	"Communication Services": "50",
+	"Utilities":              "55",
+	"Real Estate":            "60",
+}
+
+// yahooIndustries maps the Yahoo industries to the GICS industry codes. An industry
+// may belong to another GICS sector than its Yahoo sector maps to, Yahoo files
+// Packaging & Containers under Consumer Cyclical and GICS under Materials
+var yahooIndustries = map[string]string{
+	"Oil & Gas Equipment & Services":         "101010",
+	"Oil & Gas Drilling":                     "101010",
+	"Oil & Gas E&P":                          "101020",
+	"Oil & Gas Integrated":                   "101020",
+	"Oil & Gas Midstream":                    "101020",
+	"Oil & Gas Refining & Marketing":         "101020",
+	"Thermal Coal":                           "101020",
+	"Uranium":                                "101020",
+	"Chemicals":                              "151010",
+	"Specialty Chemicals":                    "151010",
+	"Agricultural Inputs":                    "151010",
+	"Building Materials":                     "151020",
+	"Packaging & Containers":                 "151030",
+	"Gold":                                   "151040",
+	"Silver":                                 "151040",
+	"Copper":                                 "151040",
+	"Steel":                                  "151040",
+	"Aluminum":                               "151040",
+	"Coking Coal":                            "151040",
+	"Other Industrial Metals & Mining":       "151040",
+	"Other Precious Metals & Mining":         "151040",
+	"Lumber & Wood Production":               "151050",
+	"Paper & Paper Products":                 "151050",
+	"Aerospace & Defense":                    "201010",
+	"Building Products & Equipment":          "201020",
+	"Engineering & Construction":             "201030",
+	"Infrastructure Operations":              "201030",
+	"Electrical Equipment & Parts":           "201040",
+	"Conglomerates":                          "201050",
+	"Specialty Industrial Machinery":         "201060",
+	"Farm & Heavy Construction Machinery":    "201060",
+	"Tools & Accessories":                    "201060",
+	"Metal Fabrication":                      "201060",
+	"Pollution & Treatment Controls":         "201060",
+	"Industrial Distribution":                "201070",
+	"Waste Management":                       "202010",
+	"Specialty Business Services":            "202010",
+	"Security & Protection Services":         "202010",
+	"Business Equipment & Supplies":          "202010",
+	"Rental & Leasing Services":              "202010",
+	"Consulting Services":                    "202020",
+	"Staffing & Employment Services":         "202020",
+	"Integrated Freight & Logistics":         "203010",
+	"Airlines":                               "203020",
+	"Marine Shipping":                        "203030",
+	"Railroads":                              "203040",
+	"Trucking":                               "203040",
+	"Airports & Air Services":                "203050",
+	"Auto Parts":                             "251010",
+	"Auto Manufacturers":                     "251020",
+	"Recreational Vehicles":                  "251020",
+	"Furnishings, Fixtures & Appliances":     "252010",
+	"Residential Construction":               "252010",
+	"Leisure":                                "252020",
+	"Textile Manufacturing":                  "252030",
+	"Apparel Manufacturing":                  "252030",
+	"Footwear & Accessories":                 "252030",
+	"Luxury Goods":                           "252030",
+	"Gambling":                               "253010",
+	"Lodging":                                "253010",
+	"Resorts & Casinos":                      "253010",
+	"Restaurants":                            "253010",
+	"Travel Services":                        "253010",
+	"Personal Services":                      "253020",
+	"Education & Training Services":          "253020",
+	"Internet Retail":                        "255030",
+	"Department Stores":                      "255030",
+	"Auto & Truck Dealerships":               "255040",
+	"Apparel Retail":                         "255040",
+	"Home Improvement Retail":                "255040",
+	"Specialty Retail":                       "255040",
+	"Discount Stores":                        "301010",
+	"Grocery Stores":                         "301010",
+	"Food Distribution":                      "301010",
+	"Pharmaceutical Retailers":               "301010",
+	"Beverages—Brewers":                      "302010",
+	"Beverages—Wineries & Distilleries":      "302010",
+	"Beverages—Non-Alcoholic":                "302010",
+	"Confectioners":                          "302020",
+	"Farm Products":                          "302020",
+	"Packaged Foods":                         "302020",
+	"Tobacco":                                "302030",
+	"Household & Personal Products":          "303010",
+	"Medical Devices":                        "351010",
+	"Medical Instruments & Supplies":         "351010",
+	"Medical Care Facilities":                "351020",
+	"Healthcare Plans":                       "351020",
+	"Medical Distribution":                   "351020",
+	"Health Information Services":            "351030",
+	"Biotechnology":                          "352010",
+	"Drug Manufacturers—General":             "352020",
+	"Drug Manufacturers—Specialty & Generic": "352020",
+	"Diagnostics & Research":                 "352030",
+	"Banks—Diversified":                      "401010",
+	"Banks—Regional":                         "401010",
+	"Financial Conglomerates":                "402010",
+	"Mortgage Finance":                       "402010",
+	"Shell Companies":                        "402010",
+	"Credit Services":                        "402020",
+	"Asset Management":                       "402030",
+	"Capital Markets":                        "402030",
+	"Financial Data & Stock Exchanges":       "402030",
+	"REIT—Mortgage":                          "402040",
+	"Insurance—Life":                         "403010",
+	"Insurance—Property & Casualty":          "403010",
+	"Insurance—Diversified":                  "403010",
+	"Insurance—Specialty":                    "403010",
+	"Insurance—Reinsurance":                  "403010",
+	"Insurance Brokers":                      "403010",
+	"Information Technology Services":        "451020",
+	"Software—Application":                   "451030",
+	"Software—Infrastructure":                "451030",
+	"Communication Equipment":                "452010",
+	"Computer Hardware":                      "452020",
+	"Consumer Electronics":                   "452020",
+	"Electronic Components":                  "452030",
+	"Scientific & Technical Instruments":     "452030",
+	"Electronics & Computer Distribution":    "452030",
+	"Semiconductors":                         "453010",
+	"Semiconductor Equipment & Materials":    "453010",
+	"Solar":                                  "453010",
+	"Telecom Services":                       "501010",
+	"Advertising Agencies":                   "502010",
+	"Broadcasting":                           "502010",
+	"Publishing":                             "502010",
+	"Entertainment":                          "502020",
+	"Electronic Gaming & Multimedia":         "502020",
+	"Internet Content & Information":         "502030",
+	"Utilities—Regulated Electric":           "551010",
+	"Utilities—Regulated Gas":                "551020",
+	"Utilities—Diversified":                  "551030",
+	"Utilities—Regulated Water":              "551040",
+	"Utilities—Independent Power Producers":  "551050",
+	"Utilities—Renewable":                    "551050",
+	"REIT—Diversified":                       "601010",
+	"REIT—Industrial":                        "601025",
+	"REIT—Hotel & Motel":                     "601030",
+	"REIT—Office":                            "601040",
+	"REIT—Healthcare Facilities":             "601050",
+	"REIT—Residential":                       "601060",
+	"REIT—Retail":                            "601070",
+	"REIT—Specialty":                         "601080",
+	"Real Estate Services":                   "602010",
+	"Real Estate—Development":                "602010",
+	"Real Estate—Diversified":                "602010",
+}
+
+// Service exposure
+type Service struct {
+	repo Repo
+	log  logger.ContextLog
+}
+
+// NewService create new service
+func NewService(r Repo, l logger.ContextLog) *Service {
+	return &Service{
+		repo: r,
+		log:  l,
+	}
+}
+
+// NewClassifier creates a classifier from the built-in mappings and the mappings of the repo,
+// which extend or replace them. The built-in mappings still apply when the repo cannot be read
+func (s *Service) NewClassifier(ctx context.Context) *Classifier {
+	c := &Classifier{
+		sectors:    make(map[string]string, len(yahooSectors)),
+		industries: make(map[string]string, len(yahooIndustries)),
+	}
+
+	for yahooSector, code := range yahooSectors {
+		c.sectors[mappingKey(yahooSector)] = code
+	}
+	for yahooIndustry, code := range yahooIndustries {
+		c.industries[mappingKey(yahooIndustry)] = code
+	}
+
+	mappings, err := s.repo.FindSectorMappings(ctx)
+	if err != nil {
+		s.log.Error(ctx, "find sector mappings failed", "error", err)
+		return c
+	}
+
+	for _, mapping := range mappings {
+		if mapping.YahooIndustry != "" {
+			if _, ok := gicsIndustries[mapping.Code]; !ok {
+				s.log.Error(ctx, "unknown industry code", "code", mapping.Code, "yahooIndustry", mapping.YahooIndustry)
+				continue
+			}
+			c.industries[mappingKey(mapping.YahooIndustry)] = mapping.Code
+			continue
+		}
+
+		if _, ok := gicsSectors[mapping.Code]; !ok || mapping.YahooSector == "" {
+			s.log.Error(ctx, "unknown sector code", "code", mapping.Code, "yahooSector", mapping.YahooSector)
+			continue
+		}
+		c.sectors[mappingKey(mapping.YahooSector)] = mapping.Code
+	}
+
+	return c
+}
+
+// Classifier maps the Yahoo sectors and industries to GICS-like classifications
+type Classifier struct {
+	sectors    map[string]string
+	industries map[string]string
+}
+
+// Classify returns the classification of a Yahoo sector and industry, and whether each of them
+// is mapped. A mapped industry classifies the profile down to the industry, under the sector of
+// the industry; otherwise a mapped sector classifies the profile down to the sector only
+func (c *Classifier) Classify(yahooSector string, yahooIndustry string) (*entities.Classification, bool, bool) {
+	sectorCode, sectorMapped := c.sectors[mappingKey(yahooSector)]
+	industryCode, industryMapped := c.industries[mappingKey(yahooIndustry)]
+
+	if industryMapped {
+		sectorCode = industryCode[:2]
+	} else if !sectorMapped {
+		return nil, false, false
+	}
+
+	classification := &entities.Classification{
+		SectorCode: sectorCode,
+		Sector:     gicsSectors[sectorCode],
+	}
+
+	if industryMapped {
+		classification.IndustryGroupCode = industryCode[:4]
+		classification.IndustryGroup = gicsIndustryGroups[industryCode[:4]]
+		classification.IndustryCode = industryCode
+		classification.Industry = gicsIndustries[industryCode]
+	}
+
+	return classification, sectorMapped, industryMapped
+}
+
+// mappingKey folds the case, the spacing and the punctuation of a Yahoo sector or industry,
+// Yahoo spells Banks—Regional with an em dash on some pages and Banks - Regional on others
+func mappingKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '&' {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
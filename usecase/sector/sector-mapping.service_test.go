@@ -0,0 +1,75 @@
+package sector
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+// mappingRepo is a sector mapping repo returning fixed mappings
+type mappingRepo []*entities.SectorMapping
+
+func (r mappingRepo) FindSectorMappings(ctx context.Context) ([]*entities.SectorMapping, error) {
+	return r, nil
+}
+
+func TestClassify(t *testing.T) {
+	zap, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer zap.Close()
+
+	s := NewService(mappingRepo{
+		{YahooIndustry: "Space Tourism", Code: "253010"},
+		{YahooSector: "Financial", Code: "40"},
+		{YahooIndustry: "Banks—Regional", Code: "402010"},
+		{YahooSector: "Nowhere", Code: "99"},
+	}, zap)
+	c := s.NewClassifier(context.Background())
+
+	tests := []struct {
+		sector, industry string
+		want             *entities.Classification
+		sectorMapped     bool
+		industryMapped   bool
+	}{
+		{
+			sector: "Technology", industry: "Consumer Electronics",
+			want:         &entities.Classification{SectorCode: "45", Sector: "Information Technology", IndustryGroupCode: "4520", IndustryGroup: "Technology Hardware & Equipment", IndustryCode: "452020", Industry: "Technology Hardware, Storage & Peripherals"},
+			sectorMapped: true, industryMapped: true,
+		},
+		{
+			// the industry decides the sector, and the spelling of the dash does not matter
+			sector: "Consumer Cyclical", industry: "Packaging & Containers",
+			want:         &entities.Classification{SectorCode: "15", Sector: "Materials", IndustryGroupCode: "1510", IndustryGroup: "Materials", IndustryCode: "151030", Industry: "Containers & Packaging"},
+			sectorMapped: true, industryMapped: true,
+		},
+		{
+			sector: "Financial Services", industry: "Banks - Regional",
+			want:         &entities.Classification{SectorCode: "40", Sector: "Financials", IndustryGroupCode: "4020", IndustryGroup: "Financial Services", IndustryCode: "402010", Industry: "Financial Services"},
+			sectorMapped: true, industryMapped: true,
+		},
+		{
+			sector: "Financial", industry: "Lending Circles",
+			want:         &entities.Classification{SectorCode: "40", Sector: "Financials"},
+			sectorMapped: true, industryMapped: false,
+		},
+		{
+			sector: "Frontier", industry: "Space Tourism",
+			want:         &entities.Classification{SectorCode: "25", Sector: "Consumer Discretionary", IndustryGroupCode: "2530", IndustryGroup: "Consumer Services", IndustryCode: "253010", Industry: "Hotels, Restaurants & Leisure"},
+			sectorMapped: false, industryMapped: true,
+		},
+		{sector: "Nowhere", industry: ""},
+	}
+
+	for _, test := range tests {
+		got, sectorMapped, industryMapped := c.Classify(test.sector, test.industry)
+		if !reflect.DeepEqual(got, test.want) || sectorMapped != test.sectorMapped || industryMapped != test.industryMapped {
+			t.Errorf("Classify(%q, %q) = %+v, %v, %v, want %+v, %v, %v", test.sector, test.industry, got, sectorMapped, industryMapped, test.want, test.sectorMapped, test.industryMapped)
+		}
+	}
+}
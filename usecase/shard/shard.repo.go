@@ -0,0 +1,32 @@
+package shard
+
+import (
+	"context"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+///////////////////////////////////////////////////////////
+// Shard Repository Interface
+///////////////////////////////////////////////////////////
+
+// Reader interface
+type Reader interface {
+}
+
+// Writer interface
+type Writer interface {
+	InsertShards(ctx context.Context, shards []*entities.Shard) error
+	CompleteShard(ctx context.Context, shard *entities.Shard) error
+}
+
+// Repo interface
+type Repo interface {
+	Reader
+	Writer
+}
+
+// Publisher hands the shard descriptors out to the workers
+type Publisher interface {
+	PublishShards(ctx context.Context, shards []*entities.Shard) error
+}
@@ -0,0 +1,73 @@
+package shard
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	logger "github.com/lenoobz/aws-lambda-logger"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/consts"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/usecase/assets"
+)
+
+// Service exposure
+type Service struct {
+	shardRepo    Repo
+	assetService *assets.Service
+	log          logger.ContextLog
+}
+
+// NewService create new service
+func NewService(shardRepo Repo, assetService *assets.Service, log logger.ContextLog) *Service {
+	return &Service{
+		shardRepo:    shardRepo,
+		assetService: assetService,
+		log:          log,
+	}
+}
+
+// CreateShards splits the assets of a source into shards of shardSize assets for a new job,
+// tracks them as pending and publishes their descriptors to the workers
+func (s *Service) CreateShards(ctx context.Context, source string, shardSize int64, publisher Publisher) ([]*entities.Shard, error) {
+	jobID := uuid.New().String()
+	s.log.Info(ctx, "creating shards", "source", source, "shardSize", shardSize, "jobId", jobID)
+
+	shards, err := s.assetService.PartitionAssetsBySource(ctx, jobID, source, shardSize)
+	if err != nil {
+		s.log.Error(ctx, "partition assets failed", "error", err)
+		return nil, err
+	}
+
+	if len(shards) == 0 {
+		return nil, nil
+	}
+
+	if err := s.shardRepo.InsertShards(ctx, shards); err != nil {
+		s.log.Error(ctx, "insert shards failed", "error", err)
+		return nil, err
+	}
+
+	if err := publisher.PublishShards(ctx, shards); err != nil {
+		s.log.Error(ctx, "publish shards failed", "error", err)
+		return nil, err
+	}
+
+	return shards, nil
+}
+
+// CompleteShard records the outcome of a shard from the run report of its worker.
+// A shard with tickers not attempted before the deadline is incomplete
+func (s *Service) CompleteShard(ctx context.Context, shard *entities.Shard, report *entities.RunReport) error {
+	completed := *shard
+	completed.Status = consts.SHARD_DONE
+	if report.NotAttempted > 0 {
+		completed.Status = consts.SHARD_INCOMPLETE
+	}
+	completed.RunID = report.RunID
+	completed.Succeeded = report.Succeeded
+	completed.Failed = report.Failed
+	completed.NotAttempted = report.NotAttempted
+
+	s.log.Info(ctx, "completing shard", "jobId", shard.JobID, "index", shard.Index, "status", completed.Status)
+	return s.shardRepo.CompleteShard(ctx, &completed)
+}
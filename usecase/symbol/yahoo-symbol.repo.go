@@ -0,0 +1,26 @@
+package symbol
+
+import (
+	"context"
+
+	"github.com/lenoobz/aws-yahoo-asset-profile-scraper/entities"
+)
+
+///////////////////////////////////////////////////////////
+// Symbol Repository Interface
+///////////////////////////////////////////////////////////
+
+// Reader interface
+type Reader interface {
+	FindSymbolOverrides(ctx context.Context, tickers []string) ([]*entities.SymbolOverride, error)
+}
+
+// Writer interface
+type Writer interface {
+}
+
+// Repo interface
+type Repo interface {
+	Reader
+	Writer
+}
@@ -0,0 +1,121 @@
+package symbol
+
+import (
+	"context"
+	"strings"
+
+	logger "github.com/lenoobz/aws-lambda-logger"
+)
+
+// exchangeSuffixes maps the exchange prefixes of our tickers, as in TSE:XYZ, to the suffixes
+// Yahoo appends to the symbols of the exchange. The US exchanges have no suffix
+var exchangeSuffixes = map[string]string{
+	"NYSE":         "",
+	"NASDAQ":       "",
+	"NYSEARCA":     "",
+	"NYSEAMERICAN": "",
+	"AMEX":         "",
+	"BATS":         "",
+	"TSE":          ".TO",
+	"TSX":          ".TO",
+	"TSXV":         ".V",
+	"CVE":          ".V",
+	"CSE":          ".CN",
+	"NEO":          ".NE",
+	"LON":          ".L",
+	"LSE":          ".L",
+	"ASX":          ".AX",
+	"ETR":          ".DE",
+	"FRA":          ".F",
+	"EPA":          ".PA",
+	"AMS":          ".AS",
+	"HKG":          ".HK",
+	"TYO":          ".T",
+}
+
+// Service exposure
+type Service struct {
+	repo Repo
+	log  logger.ContextLog
+}
+
+// NewService create new service
+func NewService(r Repo, l logger.ContextLog) *Service {
+	return &Service{
+		repo: r,
+		log:  l,
+	}
+}
+
+// ResolveYahooSymbols maps tickers to their Yahoo symbols, from the overrides first and
+// from the exchange rules otherwise. The rules still apply when the overrides cannot be read
+func (s *Service) ResolveYahooSymbols(ctx context.Context, tickers []string) map[string]string {
+	symbols := make(map[string]string, len(tickers))
+	for _, ticker := range tickers {
+		symbols[ticker] = ToYahooSymbol(ticker)
+	}
+
+	overrides, err := s.repo.FindSymbolOverrides(ctx, tickers)
+	if err != nil {
+		s.log.Error(ctx, "find symbol overrides failed", "error", err)
+		return symbols
+	}
+
+	for _, override := range overrides {
+		if _, ok := symbols[override.Ticker]; ok && override.YahooSymbol != "" {
+			symbols[override.Ticker] = override.YahooSymbol
+		}
+	}
+
+	return symbols
+}
+
+// ToYahooSymbol spells a ticker of our assets the way Yahoo does: the exchange prefix becomes
+// the suffix of the exchange, TSE:XYZ is XYZ.TO, and share classes take a dash, BRK.B is BRK-B
+func ToYahooSymbol(ticker string) string {
+	symbol := strings.ToUpper(strings.TrimSpace(ticker))
+
+	suffix := ""
+	if i := strings.Index(symbol, ":"); i >= 0 {
+		suffix = exchangeSuffixes[symbol[:i]]
+		symbol = symbol[i+1:]
+	}
+
+	// the symbol of LON:BT.L already ends with the suffix of its exchange
+	if suffix != "" && strings.HasSuffix(symbol, suffix) {
+		return symbol
+	}
+
+	// a single letter after the last dot is a share class, unless the symbol carries no
+	// exchange suffix yet and the letter is one, as in XYZ.V or BT.L
+	if i := strings.LastIndex(symbol, "."); i > 0 && i == len(symbol)-2 {
+		if suffix != "" || !isExchangeSuffix(symbol[i:]) {
+			symbol = symbol[:i] + "-" + symbol[i+1:]
+		}
+	}
+
+	return symbol + suffix
+}
+
+// isExchangeSuffix reports whether suffix is the suffix Yahoo appends to the symbols of an exchange
+func isExchangeSuffix(suffix string) bool {
+	for _, exchangeSuffix := range exchangeSuffixes {
+		if exchangeSuffix != "" && exchangeSuffix == suffix {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Exchange returns the exchange prefix of a ticker of our assets, TSE for TSE:XYZ,
+// or an empty string when the ticker has none
+func Exchange(ticker string) string {
+	symbol := strings.ToUpper(strings.TrimSpace(ticker))
+
+	if i := strings.Index(symbol, ":"); i >= 0 {
+		return symbol[:i]
+	}
+
+	return ""
+}
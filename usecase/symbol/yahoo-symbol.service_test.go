@@ -0,0 +1,35 @@
+package symbol
+
+import "testing"
+
+func TestToYahooSymbol(t *testing.T) {
+	tests := []struct {
+		ticker string
+		want   string
+	}{
+		{ticker: "AAPL", want: "AAPL"},
+		{ticker: "NASDAQ:AAPL", want: "AAPL"},
+		{ticker: "TSE:XYZ", want: "XYZ.TO"},
+		{ticker: "TSE:RCI.B", want: "RCI-B.TO"},
+		{ticker: "tsxv:abc", want: "ABC.V"},
+		{ticker: "BRK.B", want: "BRK-B"},
+		{ticker: "XYZ.TO", want: "XYZ.TO"},
+		{ticker: "LON:BT.A", want: "BT-A.L"},
+		{ticker: "OTC:ABCD", want: "ABCD"},
+		{ticker: "XYZ.V", want: "XYZ.V"},
+		{ticker: "BT.L", want: "BT.L"},
+		{ticker: "7203.T", want: "7203.T"},
+		{ticker: "SAP.F", want: "SAP.F"},
+		{ticker: "BRK.A", want: "BRK-A"},
+		{ticker: "TSE:CTC.A", want: "CTC-A.TO"},
+		{ticker: "TSXV:ABC.V", want: "ABC.V"},
+		{ticker: "LON:BT.L", want: "BT.L"},
+		{ticker: "TSE:XYZ.TO", want: "XYZ.TO"},
+	}
+
+	for _, test := range tests {
+		if got := ToYahooSymbol(test.ticker); got != test.want {
+			t.Errorf("ToYahooSymbol(%q) = %q, want %q", test.ticker, got, test.want)
+		}
+	}
+}